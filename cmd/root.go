@@ -16,6 +16,7 @@ package cmd
 
 import (
 	goflag "flag"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
@@ -23,8 +24,10 @@ import (
 )
 
 type options struct {
-	Config string
-	Port   string
+	Config          string
+	ConfigDir       string
+	Port            string
+	RefreshInterval time.Duration
 }
 
 var opts = options{}
@@ -40,7 +43,7 @@ func newRootCmd() *cobra.Command {
 		Use:   "service-prober",
 		Short: "Kubernetes liveness and readiness probe tool",
 		Run: func(cmd *cobra.Command, args []string) {
-			if opts.Config == "" {
+			if opts.Config == "" && opts.ConfigDir == "" {
 				cmd.Help()
 			} else {
 				runProber(opts)
@@ -49,9 +52,12 @@ func newRootCmd() *cobra.Command {
 	}
 
 	flags := cmd.Flags()
-	flags.StringVar(&opts.Config, "config", "", "config file")
+	flags.StringVar(&opts.Config, "config", "", "config file, - for stdin, or http(s) URL")
+	flags.StringVar(&opts.ConfigDir, "config-dir", "", "directory of config files to merge, sharing their defaults and templates")
 	flags.StringVar(&opts.Port, "port", "10000", "serve port")
+	flags.DurationVar(&opts.RefreshInterval, "config-refresh-interval", time.Minute, "how often a config URL is fetched again, 0 to disable")
 	cmd.PersistentFlags().AddGoFlagSet(goflag.CommandLine)
+	cmd.AddCommand(newSchemaCmd())
 	return cmd
 }
 
@@ -62,7 +68,7 @@ func Run() error {
 }
 
 func runProber(opts options) {
-	err := prober.Prober(opts.Config, opts.Port)
+	err := prober.Prober(opts.Config, opts.ConfigDir, opts.Port, opts.RefreshInterval)
 	if err != nil {
 		glog.Fatal(err)
 		panic(err)
@@ -0,0 +1,94 @@
+package disk
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Threshold is the minimum free space of a filesystem. Zero fields are not
+// checked.
+type Threshold struct {
+	FreePercent       float64
+	FreeBytes         uint64
+	FreeInodesPercent float64
+	FreeInodes        uint64
+}
+
+// usage is the space of a filesystem as reported by statfs.
+type usage struct {
+	totalBytes  uint64
+	freeBytes   uint64
+	totalInodes uint64
+	freeInodes  uint64
+}
+
+// New creates a DiskProber
+func New() DiskProber {
+	return diskProber{}
+}
+
+// DiskProber checks the free space of the filesystem mounted at a path
+type DiskProber interface {
+	Probe(path string, threshold Threshold) (probe.Result, string, error)
+}
+
+type diskProber struct{}
+
+// Probe fails when the free space or the free inodes of the filesystem
+// containing path drop below threshold.
+func (pr diskProber) Probe(path string, threshold Threshold) (probe.Result, string, error) {
+	u, err := statfs(path)
+	if err != nil {
+		return probe.Failure, err.Error(), nil
+	}
+	return evaluate(u, threshold)
+}
+
+func evaluate(u usage, threshold Threshold) (probe.Result, string, error) {
+	var problems []string
+	freePercent := percent(u.freeBytes, u.totalBytes)
+	output := fmt.Sprintf("%.1f%% free (%s of %s)", freePercent, formatBytes(u.freeBytes), formatBytes(u.totalBytes))
+	if threshold.FreePercent > 0 && freePercent < threshold.FreePercent {
+		problems = append(problems, fmt.Sprintf("free space below %.1f%%", threshold.FreePercent))
+	}
+	if threshold.FreeBytes > 0 && u.freeBytes < threshold.FreeBytes {
+		problems = append(problems, fmt.Sprintf("free space below %s", formatBytes(threshold.FreeBytes)))
+	}
+	// Some filesystems, such as btrfs, do not report inodes.
+	if u.totalInodes > 0 {
+		freeInodesPercent := percent(u.freeInodes, u.totalInodes)
+		output += fmt.Sprintf(", %.1f%% inodes free (%d of %d)", freeInodesPercent, u.freeInodes, u.totalInodes)
+		if threshold.FreeInodesPercent > 0 && freeInodesPercent < threshold.FreeInodesPercent {
+			problems = append(problems, fmt.Sprintf("free inodes below %.1f%%", threshold.FreeInodesPercent))
+		}
+		if threshold.FreeInodes > 0 && u.freeInodes < threshold.FreeInodes {
+			problems = append(problems, fmt.Sprintf("free inodes below %d", threshold.FreeInodes))
+		}
+	}
+	if len(problems) > 0 {
+		return probe.Failure, strings.Join(problems, ", ") + ": " + output, nil
+	}
+	return probe.Success, output, nil
+}
+
+func percent(free, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(free) / float64(total) * 100
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,68 @@
+package disk
+
+import (
+	"runtime"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestEvaluate(t *testing.T) {
+	const gi = 1 << 30
+	tests := []struct {
+		usage          usage
+		threshold      Threshold
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{
+			usage{100 * gi, 20 * gi, 1000, 900},
+			Threshold{FreePercent: 10, FreeInodesPercent: 10},
+			probe.Success,
+			"20.0% free (20.0 GiB of 100.0 GiB), 90.0% inodes free (900 of 1000)",
+		},
+		{
+			usage{100 * gi, 5 * gi, 1000, 900},
+			Threshold{FreePercent: 10},
+			probe.Failure,
+			"free space below 10.0%: 5.0% free (5.0 GiB of 100.0 GiB), 90.0% inodes free (900 of 1000)",
+		},
+		{
+			usage{100 * gi, 20 * gi, 1000, 50},
+			Threshold{FreeBytes: 30 * gi, FreeInodes: 100},
+			probe.Failure,
+			"free space below 30.0 GiB, free inodes below 100: 20.0% free (20.0 GiB of 100.0 GiB), 5.0% inodes free (50 of 1000)",
+		},
+		{
+			usage{100 * gi, 20 * gi, 0, 0},
+			Threshold{FreeInodesPercent: 10},
+			probe.Success,
+			"20.0% free (20.0 GiB of 100.0 GiB)",
+		},
+	}
+	for i, tt := range tests {
+		result, output, err := evaluate(tt.usage, tt.threshold)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if output != tt.expectedOutput {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("statfs is only supported on linux")
+	}
+	prober := New()
+	if result, output, _ := prober.Probe("/", Threshold{}); result != probe.Success {
+		t.Errorf("expected result=%v, get=%v (%s)", probe.Success, result, output)
+	}
+	if result, _, _ := prober.Probe("/nonexistent", Threshold{}); result != probe.Failure {
+		t.Errorf("expected result=%v, get=%v", probe.Failure, result)
+	}
+}
@@ -0,0 +1,18 @@
+package disk
+
+import "golang.org/x/sys/unix"
+
+func statfs(path string) (usage, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return usage{}, err
+	}
+	// Bavail rather than Bfree, blocks reserved for root are not usable by
+	// the probed service.
+	return usage{
+		totalBytes:  stat.Blocks * uint64(stat.Bsize),
+		freeBytes:   stat.Bavail * uint64(stat.Bsize),
+		totalInodes: stat.Files,
+		freeInodes:  stat.Ffree,
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package disk
+
+import (
+	"errors"
+	"runtime"
+)
+
+func statfs(path string) (usage, error) {
+	return usage{}, errors.New("disk probe is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,186 @@
+package etcd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+const (
+	statusPath = "/v3/maintenance/status"
+	healthPath = "/health"
+)
+
+// New creates an EtcdProber
+func New() EtcdProber {
+	return etcdProber{}
+}
+
+// EtcdProber checks the quorum of an etcd cluster
+type EtcdProber interface {
+	Probe(endpoints []*url.URL, tlsConfig *tls.Config, maxRaftIndexLag uint64, timeout time.Duration) (probe.Result, string, error)
+}
+
+type etcdProber struct{}
+
+// Probe queries every member of the cluster and checks that a quorum of
+// members is healthy, that they agree on the leader and that their raft
+// indexes are within maxRaftIndexLag of each other. A zero maxRaftIndexLag
+// disables the raft index check. tlsConfig is used for https endpoints, the
+// default config verifies the members against the system roots.
+func (pr etcdProber) Probe(endpoints []*url.URL, tlsConfig *tls.Config, maxRaftIndexLag uint64, timeout time.Duration) (probe.Result, string, error) {
+	transport := &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+	members := make([]member, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *url.URL) {
+			defer wg.Done()
+			members[i] = queryMember(client, endpoint)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	return evaluate(members, maxRaftIndexLag)
+}
+
+// member is the state reported by a single etcd member.
+type member struct {
+	endpoint string
+	// status is false when the member only exposes /health, in which case
+	// leader and raftIndex are unknown.
+	status    bool
+	leader    uint64
+	raftIndex uint64
+	err       error
+}
+
+type statusResponse struct {
+	Leader    uint64 `json:"leader,string"`
+	RaftIndex uint64 `json:"raftIndex,string"`
+}
+
+type healthResponse struct {
+	Health string `json:"health"`
+}
+
+func queryMember(client *http.Client, endpoint *url.URL) member {
+	m := member{endpoint: endpoint.String()}
+	body, code, err := do(client, "POST", endpoint, statusPath, []byte("{}"))
+	if err != nil {
+		m.err = err
+		return m
+	}
+	if code == http.StatusOK {
+		var status statusResponse
+		if err := json.Unmarshal(body, &status); err != nil {
+			m.err = fmt.Errorf("invalid status response: %v", err)
+			return m
+		}
+		m.status = true
+		m.leader = status.Leader
+		m.raftIndex = status.RaftIndex
+		if m.leader == 0 {
+			m.err = fmt.Errorf("no leader")
+		}
+		return m
+	}
+	// The maintenance API is served by the grpc-gateway, which may be
+	// disabled, so fall back to the plain health endpoint.
+	glog.V(4).Infof("etcd status API unavailable on %s (statuscode %d), falling back to %s", m.endpoint, code, healthPath)
+	body, code, err = do(client, "GET", endpoint, healthPath, nil)
+	if err != nil {
+		m.err = err
+		return m
+	}
+	var health healthResponse
+	if err := json.Unmarshal(body, &health); err != nil || code != http.StatusOK || health.Health != "true" {
+		m.err = fmt.Errorf("unhealthy, statuscode: %d, body: %s", code, strings.TrimSpace(string(body)))
+	}
+	return m
+}
+
+func do(client *http.Client, method string, endpoint *url.URL, path string, body []byte) ([]byte, int, error) {
+	u := *endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + path
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, res.StatusCode, nil
+}
+
+func evaluate(members []member, maxRaftIndexLag uint64) (probe.Result, string, error) {
+	var (
+		healthy   []member
+		problems  []string
+		leader    uint64
+		minIndex  uint64
+		maxIndex  uint64
+		hasStatus bool
+	)
+	for _, m := range members {
+		if m.err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", m.endpoint, m.err))
+			continue
+		}
+		healthy = append(healthy, m)
+		if !m.status {
+			continue
+		}
+		if !hasStatus {
+			leader, minIndex, maxIndex = m.leader, m.raftIndex, m.raftIndex
+			hasStatus = true
+			continue
+		}
+		if m.leader != leader {
+			return probe.Failure, fmt.Sprintf("etcd members disagree on leader: %x and %x", leader, m.leader), nil
+		}
+		if m.raftIndex < minIndex {
+			minIndex = m.raftIndex
+		}
+		if m.raftIndex > maxIndex {
+			maxIndex = m.raftIndex
+		}
+	}
+
+	quorum := len(members)/2 + 1
+	if len(healthy) < quorum {
+		return probe.Failure, fmt.Sprintf("etcd quorum lost: %d/%d members healthy (%s)",
+			len(healthy), len(members), strings.Join(problems, "; ")), nil
+	}
+	if maxRaftIndexLag > 0 && maxIndex-minIndex > maxRaftIndexLag {
+		return probe.Failure, fmt.Sprintf("etcd raft index lag %d exceeds %d", maxIndex-minIndex, maxRaftIndexLag), nil
+	}
+
+	output := fmt.Sprintf("%d/%d members healthy", len(healthy), len(members))
+	if hasStatus {
+		output += fmt.Sprintf(", leader %x, raft index %d", leader, maxIndex)
+	}
+	if len(problems) > 0 {
+		output += " (" + strings.Join(problems, "; ") + ")"
+	}
+	return probe.Success, output, nil
+}
@@ -0,0 +1,152 @@
+package etcd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func statusServer(leader string, raftIndex int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != statusPath || r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"header":{"cluster_id":"1"},"version":"3.3.0","leader":"%s","raftIndex":"%d"}`, leader, raftIndex)
+	}))
+}
+
+func healthServer(health string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthPath {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"health":"%s"}`, health)
+	}))
+}
+
+func TestProbe(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	tests := []struct {
+		servers         []*httptest.Server
+		maxRaftIndexLag uint64
+		expectedResult  probe.Result
+		expectedOutput  string
+	}{
+		{
+			[]*httptest.Server{statusServer("42", 100), statusServer("42", 101), statusServer("42", 100)},
+			0,
+			probe.Success,
+			"3/3 members healthy, leader 2a, raft index 101",
+		},
+		{
+			[]*httptest.Server{statusServer("42", 100), statusServer("42", 100), down},
+			0,
+			probe.Success,
+			"2/3 members healthy",
+		},
+		{
+			[]*httptest.Server{statusServer("42", 100), down, down},
+			0,
+			probe.Failure,
+			"etcd quorum lost: 1/3 members healthy",
+		},
+		{
+			[]*httptest.Server{statusServer("0", 100), statusServer("0", 100), statusServer("42", 100)},
+			0,
+			probe.Failure,
+			"etcd quorum lost: 1/3 members healthy",
+		},
+		{
+			[]*httptest.Server{statusServer("42", 100), statusServer("43", 100), statusServer("42", 100)},
+			0,
+			probe.Failure,
+			"etcd members disagree on leader",
+		},
+		{
+			[]*httptest.Server{statusServer("42", 100), statusServer("42", 500), statusServer("42", 100)},
+			100,
+			probe.Failure,
+			"etcd raft index lag 400 exceeds 100",
+		},
+		{
+			[]*httptest.Server{healthServer("true")},
+			0,
+			probe.Success,
+			"1/1 members healthy",
+		},
+		{
+			[]*httptest.Server{healthServer("false")},
+			0,
+			probe.Failure,
+			"etcd quorum lost: 0/1 members healthy",
+		},
+	}
+	prober := New()
+	for i, tt := range tests {
+		var endpoints []*url.URL
+		for _, server := range tt.servers {
+			u, _ := url.Parse(server.URL)
+			endpoints = append(endpoints, u)
+		}
+		result, output, err := prober.Probe(endpoints, nil, tt.maxRaftIndexLag, time.Second)
+		for _, server := range tt.servers {
+			server.Close()
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"leader":"42","raftIndex":"100"}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := server.TLS.Certificates[0]
+
+	tests := []struct {
+		tlsConfig      *tls.Config
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{client}}, probe.Success, "1/1 members healthy"},
+		{nil, probe.Failure, "etcd quorum lost: 0/1 members healthy"},
+		{&tls.Config{RootCAs: roots}, probe.Failure, "etcd quorum lost: 0/1 members healthy"},
+		{&tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{client}}, probe.Success, "1/1 members healthy"},
+	}
+	for i, tt := range tests {
+		result, output, err := New().Probe([]*url.URL{u}, tt.tlsConfig, 0, time.Second)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, result, output)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
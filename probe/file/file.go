@@ -0,0 +1,98 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// New creates a FileProber
+func New() FileProber {
+	return fileProber{}
+}
+
+// FileProber checks that a path exists and is usable
+type FileProber interface {
+	Probe(path string, readable bool, writable bool, maxAge time.Duration) (probe.Result, string, error)
+}
+
+type fileProber struct{}
+
+// Probe checks that path exists and, as requested, that it can be read, that
+// it can be written and that it has been modified within maxAge. A directory
+// is writable when a temporary file can be created, read back and removed in
+// it. A zero maxAge disables the age check.
+func (pr fileProber) Probe(path string, readable bool, writable bool, maxAge time.Duration) (probe.Result, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return probe.Failure, err.Error(), nil
+	}
+	if readable {
+		if err := checkReadable(path, info); err != nil {
+			return probe.Failure, "not readable: " + err.Error(), nil
+		}
+	}
+	if writable {
+		if err := checkWritable(path, info); err != nil {
+			return probe.Failure, "not writable: " + err.Error(), nil
+		}
+	}
+	age := time.Since(info.ModTime())
+	if maxAge > 0 && age > maxAge {
+		return probe.Failure, fmt.Sprintf("%s modified %s ago, max age %s", path, age.Round(time.Second), maxAge), nil
+	}
+	return probe.Success, fmt.Sprintf("%s modified %s ago", path, age.Round(time.Second)), nil
+}
+
+func checkReadable(path string, info os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if info.IsDir() {
+		_, err = f.Readdirnames(1)
+	} else {
+		_, err = f.Read(make([]byte, 1))
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func checkWritable(path string, info os.FileInfo) error {
+	if !info.IsDir() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	f, err := ioutil.TempFile(path, ".service-prober-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	content := []byte(time.Now().String())
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	read, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(read, content) {
+		return fmt.Errorf("read back %d bytes, wrote %d", len(read), len(content))
+	}
+	return os.Remove(f.Name())
+}
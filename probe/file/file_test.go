@@ -0,0 +1,69 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	heartbeat := filepath.Join(dir, "heartbeat")
+	if err := ioutil.WriteFile(heartbeat, []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "stale")
+	if err := ioutil.WriteFile(stale, []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path           string
+		readable       bool
+		writable       bool
+		maxAge         time.Duration
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{heartbeat, true, true, time.Minute, probe.Success, heartbeat + " modified"},
+		{dir, true, true, 0, probe.Success, dir + " modified"},
+		{filepath.Join(dir, "missing"), false, false, 0, probe.Failure, "stat " + filepath.Join(dir, "missing")},
+		{stale, true, false, time.Minute, probe.Failure, stale + " modified 1h0m0s ago, max age 1m0s"},
+		{stale, true, false, 2 * time.Hour, probe.Success, stale + " modified 1h0m0s ago"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		result, output, err := prober.Probe(tt.path, tt.readable, tt.writable, tt.maxAge)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, result, output)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+
+	// The writable round trip must not leave files behind.
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 files in %s, get %d", dir, len(files))
+	}
+}
@@ -0,0 +1,70 @@
+package ftp
+
+import (
+	"net"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// New creates a FTPProber
+func New() FTPProber {
+	return ftpProber{}
+}
+
+// FTPProber checks the banner and optionally the login of a FTP server
+type FTPProber interface {
+	Probe(host string, port int, user string, password string, timeout time.Duration) (probe.Result, string, error)
+}
+
+type ftpProber struct{}
+
+// Probe reads the 220 banner. When user is not empty it also logs in with
+// user and password.
+func (pr ftpProber) Probe(host string, port int, user string, password string, timeout time.Duration) (probe.Result, string, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		// Convert errors to failures to handle timeouts.
+		return probe.Failure, err.Error(), nil
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		return probe.Failure, "unexpected banner: " + err.Error(), nil
+	}
+	if user != "" {
+		code, msg, err := cmd(text, 0, "USER %s", user)
+		if err != nil {
+			return probe.Failure, "USER failed: " + err.Error(), nil
+		}
+		switch code {
+		case 230:
+			// Logged in without a password.
+		case 331, 332:
+			if _, _, err := cmd(text, 230, "PASS %s", password); err != nil {
+				return probe.Failure, "login failed: " + err.Error(), nil
+			}
+		default:
+			return probe.Failure, "USER failed: " + strconv.Itoa(code) + " " + msg, nil
+		}
+	}
+	cmd(text, 221, "QUIT")
+	return probe.Success, banner, nil
+}
+
+func cmd(text *textproto.Conn, expectCode int, format string, args ...interface{}) (int, string, error) {
+	id, err := text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	return text.ReadResponse(expectCode)
+}
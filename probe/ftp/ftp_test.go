@@ -0,0 +1,96 @@
+package ftp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// fakeServer speaks just enough FTP for the prober.
+func fakeServer(t *testing.T, banner string, user string, password string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				io.WriteString(conn, banner)
+				r := bufio.NewReader(conn)
+				login := ""
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					arg := ""
+					if len(fields) > 1 {
+						arg = fields[1]
+					}
+					switch fields[0] {
+					case "USER":
+						login = arg
+						io.WriteString(conn, "331 password required\r\n")
+					case "PASS":
+						if login == user && arg == password {
+							io.WriteString(conn, "230 logged in\r\n")
+						} else {
+							io.WriteString(conn, "530 login incorrect\r\n")
+						}
+					case "QUIT":
+						io.WriteString(conn, "221 bye\r\n")
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		banner         string
+		user           string
+		password       string
+		timeout        time.Duration
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{"220 ProFTPD Server ready\r\n", "", "", time.Second, probe.Success, "ProFTPD Server ready"},
+		{"220 ProFTPD Server ready\r\n", "", "", 0, probe.Success, "ProFTPD Server ready"},
+		{"220-Welcome\r\n220 ProFTPD Server ready\r\n", "", "", time.Second, probe.Success, "Welcome\nProFTPD Server ready"},
+		{"421 too many users\r\n", "", "", time.Second, probe.Failure, "unexpected banner: 421"},
+		{"220 ProFTPD Server ready\r\n", "probe", "secret", time.Second, probe.Success, "ProFTPD Server ready"},
+		{"220 ProFTPD Server ready\r\n", "probe", "wrong", time.Second, probe.Failure, "login failed: 530"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		l := fakeServer(t, tt.banner, "probe", "secret")
+		host, port, _ := net.SplitHostPort(l.Addr().String())
+		portNum, _ := strconv.Atoi(port)
+		result, output, err := prober.Probe(host, portNum, tt.user, tt.password, tt.timeout)
+		l.Close()
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
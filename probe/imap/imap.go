@@ -0,0 +1,108 @@
+package imap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// New creates an IMAPProber
+func New() IMAPProber {
+	return imapProber{}
+}
+
+// IMAPProber checks the greeting and capabilities of an IMAP server
+type IMAPProber interface {
+	Probe(host string, port int, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error)
+}
+
+type imapProber struct{}
+
+// Probe reads the "* OK" greeting and checks that the server announces
+// IMAP4rev1 in its CAPABILITY response. When tlsConfig is not nil the
+// connection uses implicit TLS, as on port 993.
+func (pr imapProber) Probe(host string, port int, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		// Convert errors to failures to handle timeouts.
+		return probe.Failure, err.Error(), nil
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	text := textproto.NewConn(conn)
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return probe.Failure, err.Error(), nil
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return probe.Failure, "unexpected greeting: " + greeting, nil
+	}
+
+	untagged, err := command(text, "a1", "CAPABILITY")
+	if err != nil {
+		return probe.Failure, "CAPABILITY failed: " + err.Error(), nil
+	}
+	var capabilities []string
+	for _, line := range untagged {
+		if strings.HasPrefix(strings.ToUpper(line), "* CAPABILITY ") {
+			capabilities = strings.Fields(line)[2:]
+		}
+	}
+	if !contains(capabilities, "IMAP4rev1") {
+		return probe.Failure, fmt.Sprintf("IMAP4rev1 not in capabilities: %s", strings.Join(capabilities, " ")), nil
+	}
+	command(text, "a2", "LOGOUT")
+	return probe.Success, greeting, nil
+}
+
+// command sends a tagged command and returns the untagged responses that
+// precede the tagged OK.
+func command(text *textproto.Conn, tag string, cmd string) ([]string, error) {
+	if err := text.PrintfLine("%s %s", tag, cmd); err != nil {
+		return nil, err
+	}
+	var untagged []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, tag+" ") {
+			untagged = append(untagged, line)
+			continue
+		}
+		status := strings.TrimPrefix(line, tag+" ")
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return nil, fmt.Errorf("%s", status)
+		}
+		return untagged, nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package imap
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// fakeServer speaks just enough IMAP for the prober.
+func fakeServer(t *testing.T, greeting string, capabilities string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				io.WriteString(conn, greeting+"\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch strings.ToUpper(fields[1]) {
+					case "CAPABILITY":
+						io.WriteString(conn, "* CAPABILITY "+capabilities+"\r\n")
+						io.WriteString(conn, fields[0]+" OK CAPABILITY completed\r\n")
+					case "LOGOUT":
+						io.WriteString(conn, "* BYE\r\n"+fields[0]+" OK LOGOUT completed\r\n")
+						return
+					default:
+						io.WriteString(conn, fields[0]+" BAD unknown command\r\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		greeting       string
+		capabilities   string
+		timeout        time.Duration
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{"* OK IMAP4rev1 Service Ready", "IMAP4rev1 STARTTLS AUTH=PLAIN", time.Second, probe.Success, "* OK IMAP4rev1 Service Ready"},
+		{"* OK IMAP4rev1 Service Ready", "IMAP4rev1 STARTTLS AUTH=PLAIN", 0, probe.Success, "* OK IMAP4rev1 Service Ready"},
+		{"* BYE too many connections", "IMAP4rev1", time.Second, probe.Failure, "unexpected greeting: * BYE too many connections"},
+		{"* OK ready", "IMAP4 AUTH=PLAIN", time.Second, probe.Failure, "IMAP4rev1 not in capabilities: IMAP4 AUTH=PLAIN"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		l := fakeServer(t, tt.greeting, tt.capabilities)
+		host, port, _ := net.SplitHostPort(l.Addr().String())
+		portNum, _ := strconv.Atoi(port)
+		result, output, err := prober.Probe(host, portNum, nil, tt.timeout)
+		l.Close()
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if output != tt.expectedOutput {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
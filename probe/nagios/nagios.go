@@ -0,0 +1,207 @@
+package nagios
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is the state reported by a plugin through its exit code.
+type Status int
+
+// The plugin return codes of the Monitoring Plugins guidelines.
+const (
+	OK Status = iota
+	Warning
+	Critical
+	Unknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// MarshalText encodes the status as its name.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name.
+func (s *Status) UnmarshalText(text []byte) error {
+	for _, status := range []Status{OK, Warning, Critical, Unknown} {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q", text)
+}
+
+// Perfdata is a single performance data value, 'label'=value[UOM];[warn];[crit];[min];[max].
+type Perfdata struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	UOM   string  `json:"uom,omitempty"`
+	Warn  string  `json:"warn,omitempty"`
+	Crit  string  `json:"crit,omitempty"`
+	Min   string  `json:"min,omitempty"`
+	Max   string  `json:"max,omitempty"`
+}
+
+// Result is the outcome of a plugin run.
+type Result struct {
+	Status   Status
+	Text     string
+	Perfdata []Perfdata
+}
+
+// waitDelay bounds how long the output of a timed out plugin is still read.
+const waitDelay = 100 * time.Millisecond
+
+// New creates a NagiosProber
+func New() NagiosProber {
+	return nagiosProber{}
+}
+
+// NagiosProber runs Nagios compatible check plugins
+type NagiosProber interface {
+	Probe(cmd []string, timeout time.Duration) (Result, error)
+}
+
+type nagiosProber struct{}
+
+// Probe runs the plugin and maps its exit code to a Status. A plugin that
+// cannot be started, times out or exits with an unexpected code is Unknown.
+// On timeout the plugin is killed with the processes it started.
+func (pr nagiosProber) Probe(cmd []string, timeout time.Duration) (Result, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var stdout bytes.Buffer
+	c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	c.Stdout = &stdout
+	killProcessGroup(c)
+	// Children that inherited stdout would otherwise keep Run waiting
+	// after the plugin is killed.
+	c.WaitDelay = waitDelay
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Result{Status: Unknown, Text: "plugin timed out after " + timeout.String()}, nil
+	}
+	status := OK
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return Result{Status: Unknown, Text: err.Error()}, nil
+		}
+		status = Unknown
+		if code := exitErr.ExitCode(); code >= 0 && code <= int(Unknown) {
+			status = Status(code)
+		}
+	}
+	result := Parse(stdout.String())
+	result.Status = status
+	return result, nil
+}
+
+// Parse splits plugin output into its text and performance data. The first
+// line is "TEXT | PERFDATA", following lines are long text, and
+// performance data may continue after a "|" in the long text.
+func Parse(output string) Result {
+	var (
+		result Result
+		texts  []string
+		perf   []string
+	)
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	inPerf := false
+	for i, line := range lines {
+		if inPerf {
+			perf = append(perf, line)
+			continue
+		}
+		parts := strings.SplitN(line, "|", 2)
+		if i == 0 || strings.TrimSpace(parts[0]) != "" {
+			texts = append(texts, strings.TrimSpace(parts[0]))
+		}
+		if len(parts) == 2 {
+			perf = append(perf, parts[1])
+			// Only the first line and the end of the long text carry
+			// performance data.
+			inPerf = i > 0
+		}
+	}
+	result.Text = strings.Join(texts, "\n")
+	result.Perfdata = parsePerfdata(strings.Join(perf, " "))
+	return result
+}
+
+func parsePerfdata(s string) []Perfdata {
+	var perfdata []Perfdata
+	for _, field := range splitPerfdata(s) {
+		eq := strings.LastIndex(field, "=")
+		if eq <= 0 {
+			continue
+		}
+		label := strings.Trim(field[:eq], "'")
+		values := strings.Split(field[eq+1:], ";")
+		number := strings.TrimRightFunc(values[0], func(r rune) bool {
+			return !strings.ContainsRune("0123456789.-+eE", r)
+		})
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			// "U" marks a value the plugin could not determine.
+			continue
+		}
+		p := Perfdata{Label: label, Value: value, UOM: values[0][len(number):]}
+		for i, dst := range []*string{&p.Warn, &p.Crit, &p.Min, &p.Max} {
+			if i+1 < len(values) {
+				*dst = values[i+1]
+			}
+		}
+		perfdata = append(perfdata, p)
+	}
+	return perfdata
+}
+
+// splitPerfdata splits on spaces outside of single quoted labels.
+func splitPerfdata(s string) []string {
+	var (
+		fields []string
+		field  []rune
+		quoted bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			field = append(field, r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if len(field) > 0 {
+				fields = append(fields, string(field))
+				field = field[:0]
+			}
+		default:
+			field = append(field, r)
+		}
+	}
+	if len(field) > 0 {
+		fields = append(fields, string(field))
+	}
+	return fields
+}
@@ -0,0 +1,103 @@
+package nagios
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		output         string
+		expectedResult Result
+	}{
+		{
+			"PING OK - Packet loss = 0%, RTA = 0.80 ms\n",
+			Result{Text: "PING OK - Packet loss = 0%, RTA = 0.80 ms"},
+		},
+		{
+			"LOAD OK - load average: 0.10, 0.20, 0.30|load1=0.100;5.000;10.000;0; load5=0.200;4.000;6.000;0;\n",
+			Result{
+				Text: "LOAD OK - load average: 0.10, 0.20, 0.30",
+				Perfdata: []Perfdata{
+					{Label: "load1", Value: 0.1, Warn: "5.000", Crit: "10.000", Min: "0", Max: ""},
+					{Label: "load5", Value: 0.2, Warn: "4.000", Crit: "6.000", Min: "0", Max: ""},
+				},
+			},
+		},
+		{
+			"DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n" +
+				"/ 15272 MB (77%);\n" +
+				"/boot 68 MB (69%); | /boot=68MB;88;93;0;98\n" +
+				"'/var log'=818MB;970;975;0;980\n",
+			Result{
+				Text: "DISK OK - free space: / 3326 MB (56%);\n/ 15272 MB (77%);\n/boot 68 MB (69%);",
+				Perfdata: []Perfdata{
+					{Label: "/", Value: 2643, UOM: "MB", Warn: "5948", Crit: "5958", Min: "0", Max: "5968"},
+					{Label: "/boot", Value: 68, UOM: "MB", Warn: "88", Crit: "93", Min: "0", Max: "98"},
+					{Label: "/var log", Value: 818, UOM: "MB", Warn: "970", Crit: "975", Min: "0", Max: "980"},
+				},
+			},
+		},
+		{
+			"HTTP CRITICAL | time=U;1;2 size=10% rate=2.5c",
+			Result{
+				Text: "HTTP CRITICAL",
+				Perfdata: []Perfdata{
+					{Label: "size", Value: 10, UOM: "%"},
+					{Label: "rate", Value: 2.5, UOM: "c"},
+				},
+			},
+		},
+	}
+	for i, tt := range tests {
+		result := Parse(tt.output)
+		if !reflect.DeepEqual(result, tt.expectedResult) {
+			t.Errorf("#%d: expected result=%+v, get=%+v", i, tt.expectedResult, result)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		cmd            []string
+		timeout        time.Duration
+		expectedStatus Status
+		expectedText   string
+	}{
+		{[]string{"sh", "-c", "echo 'OK - fine|value=1'"}, time.Second, OK, "OK - fine"},
+		{[]string{"sh", "-c", "echo 'WARNING - slow'; exit 1"}, time.Second, Warning, "WARNING - slow"},
+		{[]string{"sh", "-c", "echo 'CRITICAL - down'; exit 2"}, time.Second, Critical, "CRITICAL - down"},
+		{[]string{"sh", "-c", "echo 'UNKNOWN - what'; exit 3"}, time.Second, Unknown, "UNKNOWN - what"},
+		{[]string{"sh", "-c", "echo 'segfault'; exit 139"}, time.Second, Unknown, "segfault"},
+		{[]string{"sleep", "1"}, 50 * time.Millisecond, Unknown, "plugin timed out after 50ms"},
+		{[]string{"/nonexistent/check_foo"}, time.Second, Unknown, "fork/exec /nonexistent/check_foo: no such file or directory"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		result, err := prober.Probe(tt.cmd, tt.timeout)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result.Status != tt.expectedStatus {
+			t.Errorf("#%d: expected status=%v, get=%v", i, tt.expectedStatus, result.Status)
+		}
+		if result.Text != tt.expectedText {
+			t.Errorf("#%d: expected text=%q, get=%q", i, tt.expectedText, result.Text)
+		}
+	}
+}
+
+func TestProbeKillsChildren(t *testing.T) {
+	start := time.Now()
+	result, err := New().Probe([]string{"sh", "-c", "sleep 3; echo OK"}, 200*time.Millisecond)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the plugin and its children killed on timeout, returned after %v", elapsed)
+	}
+	if result.Status != Unknown || result.Text != "plugin timed out after 200ms" {
+		t.Errorf("expected a timed out plugin, get=%+v", result)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package nagios
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup starts the plugin in its own process group, and kills
+// the whole group when the plugin times out, so that the children of a
+// script do not outlive it.
+func killProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
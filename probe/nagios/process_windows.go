@@ -0,0 +1,7 @@
+package nagios
+
+import "os/exec"
+
+// killProcessGroup leaves the children of the plugin to WaitDelay, as there
+// are no process groups to kill.
+func killProcessGroup(c *exec.Cmd) {}
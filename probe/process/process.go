@@ -0,0 +1,261 @@
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// userHZ is the unit of the times in /proc/<pid>/stat, which is 100 on all
+// the architectures Linux supports.
+const userHZ = 100
+
+// Selector chooses the processes to check. Processes must match every
+// non-empty field.
+type Selector struct {
+	// Name is matched against the command name of the process.
+	Name    string
+	Cmdline *regexp.Regexp
+	PIDFile string
+}
+
+// Limits are the maximum resources the processes may use. Zero fields are
+// not checked.
+type Limits struct {
+	MaxRSS       uint64
+	MaxOpenFiles int
+}
+
+// New creates a ProcessProber
+func New() ProcessProber {
+	return processProber{procRoot: "/proc"}
+}
+
+// ProcessProber checks that a process is running by scanning /proc
+type ProcessProber interface {
+	Probe(selector Selector, limits Limits) (probe.Result, string, error)
+}
+
+type processProber struct {
+	procRoot string
+}
+
+// process is the state of a process read from /proc.
+type process struct {
+	pid   int
+	name  string
+	state string
+	// startTicks is the start time in clock ticks since boot.
+	startTicks uint64
+	uptime     time.Duration
+	rss        uint64
+	openFiles  int
+	// openFilesErr is set when the fd directory of the process cannot be
+	// read, usually because it belongs to another user.
+	openFilesErr error
+}
+
+// Probe succeeds when at least one process matches selector, and every
+// matching process is alive and within limits.
+func (pr processProber) Probe(selector Selector, limits Limits) (probe.Result, string, error) {
+	pids, err := pr.candidates(selector)
+	if err != nil {
+		return probe.Failure, err.Error(), nil
+	}
+	var (
+		running []process
+		zombies []string
+	)
+	for _, pid := range pids {
+		p, err := pr.stat(pid)
+		if err != nil {
+			// The process exited while scanning.
+			continue
+		}
+		if !pr.matches(selector, p) {
+			continue
+		}
+		if p.state == "Z" || p.state == "X" {
+			zombies = append(zombies, strconv.Itoa(p.pid))
+			continue
+		}
+		pr.usage(&p)
+		running = append(running, p)
+	}
+	if len(running) == 0 {
+		if len(zombies) > 0 {
+			return probe.Failure, "process is a zombie: pid " + strings.Join(zombies, ", "), nil
+		}
+		return probe.Failure, "no matching process", nil
+	}
+
+	var outputs []string
+	result := probe.Success
+	for _, p := range running {
+		output := p.String()
+		if limits.MaxRSS > 0 && p.rss > limits.MaxRSS {
+			result = probe.Failure
+			output += fmt.Sprintf(": rss exceeds %d kB", limits.MaxRSS/1024)
+		}
+		if limits.MaxOpenFiles > 0 {
+			if p.openFilesErr != nil {
+				result = probe.Failure
+				output += ": cannot count open files: " + p.openFilesErr.Error()
+			} else if p.openFiles > limits.MaxOpenFiles {
+				result = probe.Failure
+				output += fmt.Sprintf(": open files exceed %d", limits.MaxOpenFiles)
+			}
+		}
+		outputs = append(outputs, output)
+	}
+	return result, strings.Join(outputs, "; "), nil
+}
+
+func (p process) String() string {
+	s := fmt.Sprintf("pid %d (%s) state %s uptime %s rss %d kB", p.pid, p.name, p.state, p.uptime, p.rss/1024)
+	if p.openFilesErr == nil {
+		s += fmt.Sprintf(" fds %d", p.openFiles)
+	}
+	return s
+}
+
+func (s Selector) matches(p process, cmdline string) bool {
+	if s.Name != "" && s.Name != p.name && s.Name != filepath.Base(strings.SplitN(cmdline, " ", 2)[0]) {
+		return false
+	}
+	if s.Cmdline != nil && !s.Cmdline.MatchString(cmdline) {
+		return false
+	}
+	return true
+}
+
+// matches reports whether the process matches the selector, reading its
+// command line only when the command name is not enough.
+func (pr processProber) matches(s Selector, p process) bool {
+	if s.Cmdline == nil && (s.Name == "" || s.Name == p.name) {
+		return true
+	}
+	return s.matches(p, pr.cmdline(p.pid))
+}
+
+// candidates returns the pid from the pid file, or every pid but our own.
+func (pr processProber) candidates(selector Selector) ([]int, error) {
+	if selector.PIDFile != "" {
+		content, err := ioutil.ReadFile(selector.PIDFile)
+		if err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pid file %s: %v", selector.PIDFile, err)
+		}
+		return []int{pid}, nil
+	}
+	names, err := ioutil.ReadDir(pr.procRoot)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	self := os.Getpid()
+	for _, name := range names {
+		pid, err := strconv.Atoi(name.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+func (pr processProber) path(pid int, name string) string {
+	return filepath.Join(pr.procRoot, strconv.Itoa(pid), name)
+}
+
+func (pr processProber) cmdline(pid int) string {
+	content, err := ioutil.ReadFile(pr.path(pid, "cmdline"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bytes.Replace(content, []byte{0}, []byte{' '}, -1)))
+}
+
+// stat reads the name, state and start time of the process from
+// /proc/<pid>/stat, which is all that is needed to select it.
+func (pr processProber) stat(pid int) (process, error) {
+	p := process{pid: pid}
+	stat, err := ioutil.ReadFile(pr.path(pid, "stat"))
+	if err != nil {
+		return p, err
+	}
+	// The command name is in parentheses and may itself contain spaces and
+	// parentheses.
+	open, end := bytes.IndexByte(stat, '('), bytes.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return p, fmt.Errorf("invalid stat of pid %d", pid)
+	}
+	p.name = string(stat[open+1 : end])
+	fields := strings.Fields(string(stat[end+1:]))
+	// fields[0] is field 3 of proc(5), the state, and fields[19] field 22,
+	// the start time in clock ticks since boot.
+	if len(fields) < 20 {
+		return p, fmt.Errorf("invalid stat of pid %d", pid)
+	}
+	p.state = fields[0]
+	p.startTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+	return p, nil
+}
+
+// usage reads the uptime, resident set size and open files of a selected
+// process.
+func (pr processProber) usage(p *process) {
+	if uptime, err := pr.uptime(); err == nil && p.startTicks > 0 {
+		p.uptime = (uptime - time.Duration(p.startTicks)*time.Second/userHZ).Round(time.Second)
+	}
+	p.rss = pr.rss(p.pid)
+	fds, err := ioutil.ReadDir(pr.path(p.pid, "fd"))
+	p.openFiles, p.openFilesErr = len(fds), err
+}
+
+// rss returns the resident set size in bytes from /proc/<pid>/status.
+func (pr processProber) rss(pid int) uint64 {
+	f, err := os.Open(pr.path(pid, "status"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			kb, _ := strconv.ParseUint(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// uptime returns the time since boot from /proc/uptime.
+func (pr processProber) uptime() (time.Duration, error) {
+	content, err := ioutil.ReadFile(filepath.Join(pr.procRoot, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid uptime %q", content)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Only the stat and cmdline of the processes that are not selected must be
+// read. Their status and fd are replaced with fifos, which block readers.
+func TestProbeReadsSelectedProcesses(t *testing.T) {
+	root := fakeProcRoot(t, []fakeProcess{
+		{100, "nginx", "S", "nginx: master process\x00", 2048, 3},
+		{200, "postgres", "S", "postgres -D /var/lib/postgresql\x00", 4096, 10},
+	})
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "200")
+	for _, name := range []string{"status", "fd"} {
+		os.RemoveAll(filepath.Join(dir, name))
+		if err := syscall.Mkfifo(filepath.Join(dir, name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan probe.Result, 1)
+	go func() {
+		result, _, _ := processProber{procRoot: root}.Probe(Selector{Name: "nginx"}, Limits{MaxOpenFiles: 10})
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		if result != probe.Success {
+			t.Errorf("expected result=%v, get=%v", probe.Success, result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the status and fd of unselected processes not to be read")
+	}
+}
@@ -0,0 +1,90 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+type fakeProcess struct {
+	pid     int
+	comm    string
+	state   string
+	cmdline string
+	rssKB   int
+	fds     int
+}
+
+// fakeProcRoot lays out the /proc files read by the prober. Every process
+// started 100 seconds after boot and the system has been up for 1 hour.
+func fakeProcRoot(t *testing.T, processes []fakeProcess) string {
+	root, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(path string, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "uptime"), "3600.00 7000.00\n")
+	for _, p := range processes {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		if err := os.MkdirAll(filepath.Join(dir, "fd"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		write(filepath.Join(dir, "stat"), strconv.Itoa(p.pid)+" ("+p.comm+") "+p.state+
+			" 1 1 1 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 1 0 10000 1000000 300 18446744073709551615\n")
+		write(filepath.Join(dir, "cmdline"), p.cmdline)
+		write(filepath.Join(dir, "status"), "Name:\t"+p.comm+"\nVmRSS:\t    "+strconv.Itoa(p.rssKB)+" kB\n")
+		for fd := 0; fd < p.fds; fd++ {
+			write(filepath.Join(dir, "fd", strconv.Itoa(fd)), "")
+		}
+	}
+	return root
+}
+
+func TestProbe(t *testing.T) {
+	root := fakeProcRoot(t, []fakeProcess{
+		{100, "nginx", "S", "nginx: master process\x00", 2048, 3},
+		{200, "worker", "Z", "", 0, 0},
+		{300, "java", "S", "/usr/bin/java\x00-jar\x00app (1).jar\x00", 512000, 40},
+	})
+	defer os.RemoveAll(root)
+	pidFile := filepath.Join(root, "app.pid")
+	ioutil.WriteFile(pidFile, []byte("300\n"), 0644)
+
+	tests := []struct {
+		selector       Selector
+		limits         Limits
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{Selector{Name: "nginx"}, Limits{}, probe.Success, "pid 100 (nginx) state S uptime 58m20s rss 2048 kB fds 3"},
+		{Selector{Name: "worker"}, Limits{}, probe.Failure, "process is a zombie: pid 200"},
+		{Selector{Name: "redis"}, Limits{}, probe.Failure, "no matching process"},
+		{Selector{Cmdline: regexp.MustCompile(`-jar app \(1\)\.jar`)}, Limits{}, probe.Success, "pid 300 (java) state S uptime 58m20s rss 512000 kB fds 40"},
+		{Selector{PIDFile: pidFile}, Limits{MaxRSS: 1 << 30, MaxOpenFiles: 100}, probe.Success, "pid 300 (java) state S uptime 58m20s rss 512000 kB fds 40"},
+		{Selector{PIDFile: pidFile}, Limits{MaxRSS: 256 << 20}, probe.Failure, "pid 300 (java) state S uptime 58m20s rss 512000 kB fds 40: rss exceeds 262144 kB"},
+		{Selector{Name: "java"}, Limits{MaxOpenFiles: 10}, probe.Failure, "pid 300 (java) state S uptime 58m20s rss 512000 kB fds 40: open files exceed 10"},
+		{Selector{PIDFile: pidFile, Name: "nginx"}, Limits{}, probe.Failure, "no matching process"},
+	}
+	prober := processProber{procRoot: root}
+	for i, tt := range tests {
+		result, output, err := prober.Probe(tt.selector, tt.limits)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if output != tt.expectedOutput {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
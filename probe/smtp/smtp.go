@@ -0,0 +1,109 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// New creates a SMTPProber
+func New() SMTPProber {
+	return smtpProber{}
+}
+
+// SMTPProber checks the greeting and EHLO of a SMTP server
+type SMTPProber interface {
+	Probe(host string, port int, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error)
+}
+
+type smtpProber struct{}
+
+// Probe reads the 220 greeting and sends EHLO. When tlsConfig is not nil the
+// connection is upgraded with STARTTLS and the certificate is verified
+// against tlsConfig.
+func (pr smtpProber) Probe(host string, port int, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		// Convert errors to failures to handle timeouts.
+		return probe.Failure, err.Error(), nil
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		return probe.Failure, "unexpected greeting: " + err.Error(), nil
+	}
+	extensions, err := ehlo(text)
+	if err != nil {
+		return probe.Failure, "EHLO failed: " + err.Error(), nil
+	}
+	output := firstLine(banner)
+	if tlsConfig != nil {
+		if _, ok := extensions["STARTTLS"]; !ok {
+			return probe.Failure, "STARTTLS not supported: " + output, nil
+		}
+		if _, _, err := cmd(text, 220, "STARTTLS"); err != nil {
+			return probe.Failure, "STARTTLS failed: " + err.Error(), nil
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return probe.Failure, "TLS handshake failed: " + err.Error(), nil
+		}
+		text = textproto.NewConn(tlsConn)
+		if _, err := ehlo(text); err != nil {
+			return probe.Failure, "EHLO after STARTTLS failed: " + err.Error(), nil
+		}
+		output += " (STARTTLS)"
+	}
+	cmd(text, 221, "QUIT")
+	return probe.Success, output, nil
+}
+
+func ehlo(text *textproto.Conn) (map[string]string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	_, msg, err := cmd(text, 250, "EHLO %s", hostname)
+	if err != nil {
+		return nil, err
+	}
+	extensions := make(map[string]string)
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(line, " ", 2)
+		args := ""
+		if len(fields) > 1 {
+			args = fields[1]
+		}
+		extensions[strings.ToUpper(fields[0])] = args
+	}
+	return extensions, nil
+}
+
+func cmd(text *textproto.Conn, expectCode int, format string, args ...interface{}) (int, string, error) {
+	id, err := text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	return text.ReadResponse(expectCode)
+}
+
+func firstLine(msg string) string {
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		return msg[:i]
+	}
+	return msg
+}
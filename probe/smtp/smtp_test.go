@@ -0,0 +1,130 @@
+package smtp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail.example.com"},
+		DNSNames:     []string{"mail.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// fakeServer speaks just enough SMTP for the prober.
+func fakeServer(t *testing.T, greeting string, startTLS bool, cert tls.Certificate) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, greeting, startTLS, cert)
+		}
+	}()
+	return l
+}
+
+func serve(conn net.Conn, greeting string, startTLS bool, cert tls.Certificate) {
+	defer func() { conn.Close() }()
+	io.WriteString(conn, greeting+"\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+		case "EHLO":
+			io.WriteString(conn, "250-mail.example.com\r\n")
+			if startTLS {
+				io.WriteString(conn, "250-STARTTLS\r\n")
+			}
+			io.WriteString(conn, "250 8BITMIME\r\n")
+		case "STARTTLS":
+			io.WriteString(conn, "220 ready\r\n")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+		case "QUIT":
+			io.WriteString(conn, "221 bye\r\n")
+			return
+		default:
+			io.WriteString(conn, "502 unknown\r\n")
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	cert := selfSignedCert(t)
+	pool := x509.NewCertPool()
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	pool.AddCert(leaf)
+	tests := []struct {
+		greeting       string
+		startTLS       bool
+		tlsConfig      *tls.Config
+		timeout        time.Duration
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{"220 mail.example.com ESMTP", false, nil, time.Second, probe.Success, "mail.example.com ESMTP"},
+		{"220 mail.example.com ESMTP", false, nil, 0, probe.Success, "mail.example.com ESMTP"},
+		{"554 no service", false, nil, time.Second, probe.Failure, "unexpected greeting: 554"},
+		{"220 mail.example.com ESMTP", false, &tls.Config{ServerName: "mail.example.com", RootCAs: pool}, time.Second, probe.Failure, "STARTTLS not supported"},
+		{"220 mail.example.com ESMTP", true, &tls.Config{ServerName: "mail.example.com", RootCAs: pool}, time.Second, probe.Success, "mail.example.com ESMTP (STARTTLS)"},
+		{"220 mail.example.com ESMTP", true, &tls.Config{ServerName: "mail.example.com"}, time.Second, probe.Failure, "TLS handshake failed"},
+		{"220 mail.example.com ESMTP", true, &tls.Config{InsecureSkipVerify: true}, time.Second, probe.Success, "mail.example.com ESMTP (STARTTLS)"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		l := fakeServer(t, tt.greeting, tt.startTLS, cert)
+		host, port, _ := net.SplitHostPort(l.Addr().String())
+		portNum, _ := strconv.Atoi(port)
+		result, output, err := prober.Probe(host, portNum, tt.tlsConfig, tt.timeout)
+		l.Close()
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, result, output)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+const clientVersion = "SSH-2.0-service-prober"
+
+// HostKeyAlgorithms lists the supported host key algorithms in order of
+// preference.
+var HostKeyAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA,
+}
+
+// errKexDone stops the handshake once the key exchange is done, before any
+// authentication is attempted.
+var errKexDone = errors.New("key exchange done")
+
+// New creates a SSHProber
+func New() SSHProber {
+	return sshProber{}
+}
+
+// SSHProber checks the transport handshake of a SSH server
+type SSHProber interface {
+	Probe(host string, port int, hostKeyAlgorithm string, fingerprint string, timeout time.Duration) (probe.Result, string, error)
+}
+
+type sshProber struct{}
+
+// Probe performs the SSH key exchange, verifies that the server signed it
+// with its host key and, when fingerprint is not empty, that the SHA256
+// fingerprint of the host key matches it. hostKeyAlgorithm restricts the
+// negotiated host key type, which is needed to pin a key when the server
+// has several. The connection is closed after the key exchange, without
+// attempting any authentication.
+func (pr sshProber) Probe(host string, port int, hostKeyAlgorithm string, fingerprint string, timeout time.Duration) (probe.Result, string, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		// Convert errors to failures to handle timeouts.
+		return probe.Failure, err.Error(), nil
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	recorder := &versionRecorder{Conn: conn}
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "service-prober",
+		ClientVersion:     clientVersion,
+		HostKeyAlgorithms: HostKeyAlgorithms,
+		// The callback runs once the server proved it holds the key, its
+		// error ends the handshake before the authentication.
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errKexDone
+		},
+	}
+	if hostKeyAlgorithm != "" {
+		config.HostKeyAlgorithms = []string{hostKeyAlgorithm}
+	}
+	client, _, _, err := ssh.NewClientConn(recorder, addr, config)
+	if err == nil {
+		client.Close()
+		return probe.Failure, "ssh: handshake did not verify the host key", nil
+	}
+	serverVersion := recorder.version()
+	if hostKey == nil {
+		if serverVersion != "" {
+			return probe.Failure, serverVersion + ": " + err.Error(), nil
+		}
+		return probe.Failure, err.Error(), nil
+	}
+	output := fmt.Sprintf("%s %s %s", serverVersion, hostKey.Type(), ssh.FingerprintSHA256(hostKey))
+	if fingerprint != "" && ssh.FingerprintSHA256(hostKey) != fingerprint {
+		return probe.Failure, "host key fingerprint mismatch: " + output, nil
+	}
+	return probe.Success, output, nil
+}
+
+// versionRecorder records what the server sends up to its version line.
+type versionRecorder struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (r *versionRecorder) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	if r.version() == "" && r.buf.Len() < 8192 {
+		r.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// version returns the version line of the server, which may follow other
+// lines, or an empty string when it was not received.
+func (r *versionRecorder) version() string {
+	lines := strings.Split(r.buf.String(), "\n")
+	// The last line is not complete.
+	for _, line := range lines[:len(lines)-1] {
+		if strings.HasPrefix(line, "SSH-") {
+			return strings.TrimSuffix(line, "\r")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+const serverVersion = "SSH-2.0-OpenSSH_7.4"
+
+// newServer starts a SSH server with the host keys, which accepts no
+// credentials like a server the prober cannot log in to.
+func newServer(t *testing.T, hostKeys ...ssh.Signer) net.Listener {
+	config := &ssh.ServerConfig{
+		ServerVersion: serverVersion,
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, io.EOF
+		},
+	}
+	for _, key := range hostKeys {
+		config.AddHostKey(key)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				io.WriteString(conn, "banner line\r\n")
+				ssh.NewServerConn(conn, config)
+			}(conn)
+		}
+	}()
+	return l
+}
+
+func signer(t *testing.T, key interface{}, err error) ssh.Signer {
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func probeServer(l net.Listener, hostKeyAlgorithm string, fingerprint string, timeout time.Duration) (probe.Result, string, error) {
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	return New().Probe(host, portNum, hostKeyAlgorithm, fingerprint, timeout)
+}
+
+func TestProbe(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	ed25519Signer := signer(t, ed25519Key, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecdsaSigner := signer(t, ecdsaKey, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSigner := signer(t, rsaKey, err)
+	ed25519Fingerprint := ssh.FingerprintSHA256(ed25519Signer.PublicKey())
+	ecdsaFingerprint := ssh.FingerprintSHA256(ecdsaSigner.PublicKey())
+	rsaFingerprint := ssh.FingerprintSHA256(rsaSigner.PublicKey())
+
+	all := newServer(t, ed25519Signer, ecdsaSigner, rsaSigner)
+	defer all.Close()
+	ed25519Only := newServer(t, ed25519Signer)
+	defer ed25519Only.Close()
+	tests := []struct {
+		server           net.Listener
+		hostKeyAlgorithm string
+		fingerprint      string
+		timeout          time.Duration
+		expectedResult   probe.Result
+		expectedOutput   string
+	}{
+		{all, "", "", time.Second, probe.Success, serverVersion + " ssh-ed25519 " + ed25519Fingerprint},
+		{all, "", "", 0, probe.Success, serverVersion + " ssh-ed25519 " + ed25519Fingerprint},
+		{all, "", ed25519Fingerprint, time.Second, probe.Success, serverVersion + " ssh-ed25519 " + ed25519Fingerprint},
+		{all, "ecdsa-sha2-nistp256", ecdsaFingerprint, time.Second, probe.Success, serverVersion + " ecdsa-sha2-nistp256 " + ecdsaFingerprint},
+		{all, "rsa-sha2-512", rsaFingerprint, time.Second, probe.Success, serverVersion + " ssh-rsa " + rsaFingerprint},
+		{all, "rsa-sha2-256", rsaFingerprint, time.Second, probe.Success, serverVersion + " ssh-rsa " + rsaFingerprint},
+		{all, "", rsaFingerprint, time.Second, probe.Failure, "host key fingerprint mismatch: " + serverVersion + " ssh-ed25519 " + ed25519Fingerprint},
+		{ed25519Only, "ssh-rsa", "", time.Second, probe.Failure, serverVersion + ": ssh: handshake failed: ssh: no common algorithm for host key"},
+	}
+	for i, tt := range tests {
+		result, output, err := probeServer(tt.server, tt.hostKeyAlgorithm, tt.fingerprint, tt.timeout)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, result, output)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
+
+// The probe must not attempt to authenticate, which would be logged as a
+// failed login on every probe.
+func TestProbeDoesNotAuthenticate(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	hostKey := signer(t, key, err)
+	attempts := make(chan string, 10)
+	handshakes := make(chan error, 1)
+	config := &ssh.ServerConfig{
+		ServerVersion: serverVersion,
+		AuthLogCallback: func(conn ssh.ConnMetadata, method string, err error) {
+			attempts <- conn.User() + " " + method
+		},
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			handshakes <- err
+			return
+		}
+		defer conn.Close()
+		_, _, _, err = ssh.NewServerConn(conn, config)
+		handshakes <- err
+	}()
+
+	result, output, err := probeServer(l, "", "", time.Second)
+	if err != nil || result != probe.Success {
+		t.Fatalf("expected success, get=%v %s %v", result, output, err)
+	}
+	select {
+	case <-handshakes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server handshake to end")
+	}
+	select {
+	case attempt := <-attempts:
+		t.Errorf("expected no authentication attempt, get=%s", attempt)
+	default:
+	}
+}
+
+// The fingerprints must match those OpenSSH prints, which users pin.
+func TestProbeFingerprintMatchesOpenSSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	dir, err := ioutil.TempDir("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "ssh_host_ed25519_key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyFile).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+	out, err := exec.Command("ssh-keygen", "-l", "-E", "sha256", "-f", keyFile+".pub").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := strings.Fields(string(out))[1]
+	pem, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.ParsePrivateKey(pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newServer(t, hostKey)
+	defer l.Close()
+	result, output, _ := probeServer(l, "", fingerprint, time.Second)
+	if result != probe.Success {
+		t.Errorf("expected result=%v with the ssh-keygen fingerprint %s, get=%v (%s)", probe.Success, fingerprint, result, output)
+	}
+}
+
+func TestProbeHangingServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	result, _, err := probeServer(l, "", "", 100*time.Millisecond)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result != probe.Failure {
+		t.Errorf("expected result=%v, get=%v", probe.Failure, result)
+	}
+}
+
+func TestVersionRecorder(t *testing.T) {
+	tests := []struct {
+		received        string
+		expectedVersion string
+	}{
+		{"SSH-2.0-OpenSSH_7.4\r\n", "SSH-2.0-OpenSSH_7.4"},
+		{"banner\r\nSSH-2.0-OpenSSH_7.4\r\n\x00\x00", "SSH-2.0-OpenSSH_7.4"},
+		{"SSH-2.0-Open", ""},
+		{"HTTP/1.1 400 Bad Request\r\n", ""},
+	}
+	for i, tt := range tests {
+		r := versionRecorder{}
+		r.buf = *bytes.NewBufferString(tt.received)
+		if version := r.version(); version != tt.expectedVersion {
+			t.Errorf("#%d: expected version=%q, get=%q", i, tt.expectedVersion, version)
+		}
+	}
+}
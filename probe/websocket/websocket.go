@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes of RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const maxFrameLength = 1 << 20
+
+// Message is sent once the handshake has completed.
+type Message struct {
+	// Ping sends Text as the payload of a ping frame and expects a pong
+	// echoing it, instead of sending a text frame.
+	Ping bool
+	Text string
+	// Expect is the text reply expected from the server. It defaults to
+	// Text, for echo endpoints.
+	Expect string
+}
+
+// New creates a WebSocketProber
+func New() WebSocketProber {
+	return webSocketProber{}
+}
+
+// WebSocketProber checks the opening handshake of a WebSocket endpoint
+type WebSocketProber interface {
+	Probe(url *url.URL, headers http.Header, subprotocol string, message *Message, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error)
+}
+
+type webSocketProber struct{}
+
+// Probe performs the RFC 6455 opening handshake on a ws or wss url. When
+// subprotocol is not empty the server must select it. When message is not
+// nil it is sent after the handshake and the reply is checked. The
+// certificate of a wss url is verified against tlsConfig, or the system
+// roots when it is nil.
+func (pr webSocketProber) Probe(u *url.URL, headers http.Header, subprotocol string, message *Message, tlsConfig *tls.Config, timeout time.Duration) (probe.Result, string, error) {
+	deadline := time.Now().Add(timeout)
+	conn, err := dial(u, tlsConfig, timeout)
+	if err != nil {
+		// Convert errors to failures to handle timeouts.
+		return probe.Failure, err.Error(), nil
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(deadline)
+	}
+
+	r := bufio.NewReader(conn)
+	output, err := handshake(conn, r, u, headers, subprotocol)
+	if err != nil {
+		return probe.Failure, err.Error(), nil
+	}
+	if message != nil {
+		reply, err := exchange(conn, r, message)
+		if err != nil {
+			return probe.Failure, output + ": " + err.Error(), nil
+		}
+		output += ", " + reply
+	}
+	writeFrame(conn, opClose, []byte{0x03, 0xe8}, true)
+	return probe.Success, output, nil
+}
+
+func dial(u *url.URL, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		return dialer.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		return tls.DialWithDialer(dialer, "tcp", host, config)
+	}
+	return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+}
+
+func handshake(conn net.Conn, r *bufio.Reader, u *url.URL, headers http.Header, subprotocol string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	if headers.Get("Host") != "" {
+		req.Host = headers.Get("Host")
+		req.Header.Del("Host")
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if subprotocol != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	if err := req.Write(conn); err != nil {
+		return "", err
+	}
+
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		res.Body.Close()
+		return "", fmt.Errorf("WebSocket probe failed with statuscode: %d", res.StatusCode)
+	}
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") {
+		return "", fmt.Errorf("unexpected Upgrade header %q", res.Header.Get("Upgrade"))
+	}
+	if !headerContains(res.Header, "Connection", "upgrade") {
+		return "", fmt.Errorf("unexpected Connection header %q", res.Header.Get("Connection"))
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	if res.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return "", errors.New("invalid Sec-WebSocket-Accept")
+	}
+	output := res.Status
+	if subprotocol != "" {
+		if got := res.Header.Get("Sec-WebSocket-Protocol"); got != subprotocol {
+			return "", fmt.Errorf("server selected subprotocol %q, expected %q", got, subprotocol)
+		}
+		output += ", subprotocol " + subprotocol
+	}
+	return output, nil
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exchange sends the message and waits for the matching reply.
+func exchange(conn net.Conn, r *bufio.Reader, message *Message) (string, error) {
+	if message.Ping {
+		if err := writeFrame(conn, opPing, []byte(message.Text), true); err != nil {
+			return "", err
+		}
+	} else if err := writeFrame(conn, opText, []byte(message.Text), true); err != nil {
+		return "", err
+	}
+	expect := message.Expect
+	if expect == "" {
+		expect = message.Text
+	}
+
+	var text []byte
+	for {
+		fin, opcode, payload, err := readFrame(r)
+		if err != nil {
+			return "", err
+		}
+		switch opcode {
+		case opPing:
+			writeFrame(conn, opPong, payload, true)
+		case opPong:
+			if message.Ping {
+				if string(payload) != message.Text {
+					return "", fmt.Errorf("pong payload %q does not match ping", payload)
+				}
+				return "pong received", nil
+			}
+		case opClose:
+			return "", errors.New("connection closed by server")
+		case opText, opContinuation:
+			if message.Ping {
+				continue
+			}
+			text = append(text, payload...)
+			if !fin {
+				continue
+			}
+			if string(text) != expect {
+				return "", fmt.Errorf("unexpected reply %q", text)
+			}
+			return "reply received", nil
+		}
+	}
+}
+
+// writeFrame writes a single unfragmented frame. Clients must mask frames.
+func writeFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	var buf bytes.Buffer
+	buf.WriteByte(0x80 | opcode)
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf.WriteByte(maskBit | byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(maskBit | 126)
+		binary.Write(&buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(maskBit | 127)
+		binary.Write(&buf, binary.BigEndian, uint64(n))
+	}
+	if masked {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		buf.Write(mask)
+		for i, b := range payload {
+			buf.WriteByte(b ^ mask[i%4])
+		}
+	} else {
+		buf.Write(payload)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readFrame(r io.Reader) (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(n)
+	case 127:
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	if length > maxFrameLength {
+		return false, 0, nil, fmt.Errorf("frame of %d bytes is too large", length)
+	}
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// echoHandler upgrades the connection and echoes text frames and pings.
+func echoHandler(subprotocol string, badAccept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + acceptGUID))
+		accept := base64.StdEncoding.EncodeToString(sum[:])
+		if badAccept {
+			accept = "invalid"
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + accept + "\r\n")
+		if subprotocol != "" {
+			rw.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+		}
+		rw.WriteString("\r\n")
+		rw.Flush()
+		for {
+			_, opcode, payload, err := readFrame(rw)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case opText:
+				writeFrame(conn, opText, payload, false)
+			case opPing:
+				writeFrame(conn, opPong, payload, false)
+			case opClose:
+				return
+			}
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		handler        http.HandlerFunc
+		header         http.Header
+		subprotocol    string
+		message        *Message
+		timeout        time.Duration
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "", nil, time.Second, probe.Success, "101 Switching Protocols"},
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "", &Message{Text: "hello"}, 0, probe.Success, "101 Switching Protocols, reply received"},
+		{echoHandler("", false), http.Header{}, "", nil, time.Second, probe.Failure, "WebSocket probe failed with statuscode: 403"},
+		{echoHandler("", true), http.Header{"X-Token": {"secret"}}, "", nil, time.Second, probe.Failure, "invalid Sec-WebSocket-Accept"},
+		{echoHandler("graphql-ws", false), http.Header{"X-Token": {"secret"}}, "graphql-ws", nil, time.Second, probe.Success, "101 Switching Protocols, subprotocol graphql-ws"},
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "graphql-ws", nil, time.Second, probe.Failure, `server selected subprotocol "", expected "graphql-ws"`},
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "", &Message{Text: "hello"}, time.Second, probe.Success, "101 Switching Protocols, reply received"},
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "", &Message{Text: "hello", Expect: "world"}, time.Second, probe.Failure, `101 Switching Protocols: unexpected reply "hello"`},
+		{echoHandler("", false), http.Header{"X-Token": {"secret"}}, "", &Message{Ping: true, Text: "ping"}, time.Second, probe.Success, "101 Switching Protocols, pong received"},
+	}
+	prober := New()
+	for i, tt := range tests {
+		server := httptest.NewServer(tt.handler)
+		u, _ := url.Parse(strings.Replace(server.URL, "http://", "ws://", 1) + "/socket")
+		result, output, err := prober.Probe(u, tt.header, tt.subprotocol, tt.message, nil, tt.timeout)
+		server.Close()
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if output != tt.expectedOutput {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
+
+func TestProbeSecure(t *testing.T) {
+	server := httptest.NewTLSServer(echoHandler("", false))
+	defer server.Close()
+	u, _ := url.Parse(strings.Replace(server.URL, "https://", "wss://", 1))
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tests := []struct {
+		tlsConfig      *tls.Config
+		expectedResult probe.Result
+		expectedOutput string
+	}{
+		{nil, probe.Failure, "x509: certificate signed by unknown authority"},
+		{&tls.Config{RootCAs: roots}, probe.Success, "101 Switching Protocols, reply received"},
+		{&tls.Config{InsecureSkipVerify: true}, probe.Success, "101 Switching Protocols, reply received"},
+	}
+	for i, tt := range tests {
+		result, output, err := New().Probe(u, http.Header{"X-Token": {"secret"}}, "", &Message{Text: "hello"}, tt.tlsConfig, time.Second)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, result, output)
+		}
+		if !strings.Contains(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
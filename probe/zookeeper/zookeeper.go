@@ -0,0 +1,158 @@
+package zookeeper
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// New creates a ZooKeeperProber
+func New() ZooKeeperProber {
+	return zookeeperProber{}
+}
+
+// ZooKeeperProber checks the quorum of a ZooKeeper ensemble
+type ZooKeeperProber interface {
+	Probe(servers []string, maxOutstandingRequests int, timeout time.Duration) (probe.Result, string, error)
+}
+
+type zookeeperProber struct{}
+
+// Probe sends the ruok and mntr four letter words to every server of the
+// ensemble and checks that a quorum of servers is serving, that the ensemble
+// has a leader and that no server has more than maxOutstandingRequests
+// outstanding requests. A zero maxOutstandingRequests disables that check.
+func (pr zookeeperProber) Probe(servers []string, maxOutstandingRequests int, timeout time.Duration) (probe.Result, string, error) {
+	states := make([]server, len(servers))
+	var wg sync.WaitGroup
+	for i, addr := range servers {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			states[i] = queryServer(addr, maxOutstandingRequests, timeout)
+		}(i, addr)
+	}
+	wg.Wait()
+	return evaluate(states)
+}
+
+// server is the state reported by a single ZooKeeper server.
+type server struct {
+	addr        string
+	state       string
+	outstanding int
+	err         error
+}
+
+func queryServer(addr string, maxOutstandingRequests int, timeout time.Duration) server {
+	s := server{addr: addr}
+	reply, err := fourLetterWord(addr, "ruok", timeout)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	if reply != "imok" {
+		s.err = fmt.Errorf("ruok replied %q", reply)
+		return s
+	}
+	reply, err = fourLetterWord(addr, "mntr", timeout)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	stats := parseMntr(reply)
+	s.state = stats["zk_server_state"]
+	if s.state == "" {
+		s.err = fmt.Errorf("mntr replied %q", reply)
+		return s
+	}
+	switch s.state {
+	case "leader", "follower", "observer", "standalone":
+	default:
+		s.err = fmt.Errorf("server state %s", s.state)
+		return s
+	}
+	s.outstanding, err = strconv.Atoi(stats["zk_outstanding_requests"])
+	if err != nil {
+		s.err = fmt.Errorf("invalid zk_outstanding_requests: %v", err)
+		return s
+	}
+	if maxOutstandingRequests > 0 && s.outstanding > maxOutstandingRequests {
+		s.err = fmt.Errorf("%d outstanding requests exceeds %d", s.outstanding, maxOutstandingRequests)
+	}
+	return s
+}
+
+func fourLetterWord(addr string, word string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if _, err := conn.Write([]byte(word)); err != nil {
+		return "", err
+	}
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(reply)), nil
+}
+
+func parseMntr(reply string) map[string]string {
+	stats := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(reply))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) == 2 {
+			stats[fields[0]] = strings.TrimSpace(fields[1])
+		}
+	}
+	return stats
+}
+
+func evaluate(servers []server) (probe.Result, string, error) {
+	var (
+		serving  int
+		leaders  []string
+		problems []string
+	)
+	for _, s := range servers {
+		if s.err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.addr, s.err))
+			continue
+		}
+		serving++
+		if s.state == "leader" || s.state == "standalone" {
+			leaders = append(leaders, s.addr)
+		}
+	}
+
+	quorum := len(servers)/2 + 1
+	if serving < quorum {
+		return probe.Failure, fmt.Sprintf("zookeeper quorum lost: %d/%d servers serving (%s)",
+			serving, len(servers), strings.Join(problems, "; ")), nil
+	}
+	if len(leaders) == 0 {
+		return probe.Failure, "zookeeper ensemble has no leader", nil
+	}
+	if len(leaders) > 1 {
+		return probe.Failure, fmt.Sprintf("zookeeper ensemble has %d leaders: %s", len(leaders), strings.Join(leaders, ", ")), nil
+	}
+
+	output := fmt.Sprintf("%d/%d servers serving, leader %s", serving, len(servers), leaders[0])
+	if len(problems) > 0 {
+		output += " (" + strings.Join(problems, "; ") + ")"
+	}
+	return probe.Success, output, nil
+}
@@ -0,0 +1,123 @@
+package zookeeper
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// fakeServer answers four letter words with canned replies until closed.
+func fakeServer(t *testing.T, replies map[string]string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			word := make([]byte, 4)
+			if _, err := io.ReadFull(conn, word); err == nil {
+				io.WriteString(conn, replies[string(word)])
+			}
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func mntr(state string, outstanding string) map[string]string {
+	return map[string]string{
+		"ruok": "imok",
+		"mntr": "zk_version\t3.4.10\nzk_server_state\t" + state + "\nzk_outstanding_requests\t" + outstanding + "\n",
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		replies                []map[string]string
+		maxOutstandingRequests int
+		timeout                time.Duration
+		expectedResult         probe.Result
+		expectedOutput         string
+	}{
+		{
+			[]map[string]string{mntr("standalone", "0")},
+			0,
+			time.Second,
+			probe.Success,
+			"1/1 servers serving, leader",
+		},
+		{
+			[]map[string]string{mntr("standalone", "0")},
+			0,
+			0,
+			probe.Success,
+			"1/1 servers serving, leader",
+		},
+		{
+			[]map[string]string{mntr("leader", "0"), mntr("follower", "0"), mntr("follower", "0")},
+			0,
+			time.Second,
+			probe.Success,
+			"3/3 servers serving, leader",
+		},
+		{
+			[]map[string]string{mntr("leader", "0"), mntr("follower", "0"), {}},
+			0,
+			time.Second,
+			probe.Success,
+			"2/3 servers serving, leader",
+		},
+		{
+			[]map[string]string{mntr("leader", "0"), {"ruok": "imok", "mntr": "This ZooKeeper instance is not currently serving requests"}, {}},
+			0,
+			time.Second,
+			probe.Failure,
+			"zookeeper quorum lost: 1/3 servers serving",
+		},
+		{
+			[]map[string]string{mntr("follower", "0"), mntr("follower", "0"), mntr("follower", "0")},
+			0,
+			time.Second,
+			probe.Failure,
+			"zookeeper ensemble has no leader",
+		},
+		{
+			[]map[string]string{mntr("leader", "50"), mntr("follower", "0"), mntr("follower", "50")},
+			10,
+			time.Second,
+			probe.Failure,
+			"zookeeper quorum lost: 1/3 servers serving",
+		},
+	}
+	prober := New()
+	for i, tt := range tests {
+		var servers []string
+		var listeners []net.Listener
+		for _, replies := range tt.replies {
+			l := fakeServer(t, replies)
+			listeners = append(listeners, l)
+			servers = append(servers, l.Addr().String())
+		}
+		result, output, err := prober.Probe(servers, tt.maxOutstandingRequests, tt.timeout)
+		for _, l := range listeners {
+			l.Close()
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expectedResult, result)
+		}
+		if !strings.HasPrefix(output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, output)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Breaker states.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// Defaults of the circuit breaker.
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerCoolDown = duration(30 * time.Second)
+)
+
+// breakerOptions enables a circuit breaker for a check. After Failures
+// consecutive failures the check fails without being probed for CoolDown,
+// then a single trial probe decides whether it closes again.
+type breakerOptions struct {
+	Failures int
+	CoolDown duration `json:"coolDown" yaml:"coolDown"`
+}
+
+func (o breakerOptions) validate(name string) error {
+	if o.Failures < 0 {
+		return errors.New(name + ": breaker failures must not be negative")
+	}
+	if o.CoolDown < 0 {
+		return errors.New(name + ": breaker coolDown must not be negative")
+	}
+	return nil
+}
+
+// breaker is the circuit breaker of a check.
+type breaker struct {
+	mu       sync.Mutex
+	options  breakerOptions
+	state    string
+	failures int
+	openedAt time.Time
+	// last is the result that opened the breaker.
+	last checkResult
+}
+
+func newBreaker(options breakerOptions) *breaker {
+	if options.Failures == 0 {
+		options.Failures = defaultBreakerFailures
+	}
+	if options.CoolDown == 0 {
+		options.CoolDown = defaultBreakerCoolDown
+	}
+	return &breaker{options: options, state: breakerClosed}
+}
+
+// allow reports whether the check may be probed. Once the cool-down has
+// passed, a single caller is let through as the trial and the breaker is
+// half-open until it records the outcome. When the check may not be probed
+// the returned result fails it.
+func (b *breaker) allow() (bool, checkResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerClosed:
+		return true, checkResult{}
+	case breakerOpen:
+		if wait := time.Duration(b.options.CoolDown) - time.Since(b.openedAt); wait > 0 {
+			return false, b.failFast(fmt.Sprintf("circuit breaker open, retry in %v", wait.Round(time.Second)))
+		}
+		b.state = breakerHalfOpen
+		return true, checkResult{}
+	}
+	return false, b.failFast("circuit breaker half-open, trial probe in progress")
+}
+
+func (b *breaker) failFast(output string) checkResult {
+	if b.last.Output != "" {
+		output += ": " + b.last.Output
+	}
+	return checkResult{Result: probe.Failure, Output: output, Breaker: b.state}
+}
+
+// record updates the breaker with the outcome of a probe and returns the
+// result with the state of the breaker.
+func (b *breaker) record(result checkResult) checkResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if result.Result == probe.Success || result.Result == warning {
+		b.state = breakerClosed
+		b.failures = 0
+	} else {
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.options.Failures {
+			b.state = breakerOpen
+			b.openedAt = time.Now()
+			b.last = result
+		}
+	}
+	result.Breaker = b.state
+	return result
+}
@@ -0,0 +1,90 @@
+package prober
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// countingTCPProber counts the probes and fails while down is set.
+type countingTCPProber struct {
+	calls *int32
+	down  *int32
+}
+
+func (p countingTCPProber) Probe(host string, port int, timeout time.Duration) (probe.Result, string, error) {
+	atomic.AddInt32(p.calls, 1)
+	if atomic.LoadInt32(p.down) != 0 {
+		return probe.Failure, "connection refused", nil
+	}
+	return probe.Success, "", nil
+}
+
+func TestBreaker(t *testing.T) {
+	coolDown := 50 * time.Millisecond
+	p := newProber(&probeConfig{
+		Service: service{
+			TCP: []tcpService{{Name: "mongo", IP: "10.0.0.1", checkOptions: checkOptions{
+				Breaker: &breakerOptions{Failures: 2, CoolDown: duration(coolDown)},
+			}}},
+		},
+	})
+	fake := countingTCPProber{calls: new(int32), down: new(int32)}
+	p.tcpProber = fake
+	tests := []struct {
+		down           bool
+		wait           time.Duration
+		expectedCalls  int32
+		expectedResult probe.Result
+		expectedState  string
+		expectedOutput string
+	}{
+		{true, 0, 1, probe.Failure, breakerClosed, "connection refused"},
+		{true, 0, 2, probe.Failure, breakerOpen, "connection refused"},
+		// Open, the check fails without being probed.
+		{false, 0, 2, probe.Failure, breakerOpen, "circuit breaker open, retry in"},
+		// The trial probe fails and opens the breaker again.
+		{true, coolDown, 3, probe.Failure, breakerOpen, "connection refused"},
+		{false, 0, 3, probe.Failure, breakerOpen, "circuit breaker open, retry in"},
+		{false, coolDown, 4, probe.Success, breakerClosed, ""},
+		{true, 0, 5, probe.Failure, breakerClosed, "connection refused"},
+	}
+	for i, tt := range tests {
+		time.Sleep(tt.wait)
+		down := int32(0)
+		if tt.down {
+			down = 1
+		}
+		atomic.StoreInt32(fake.down, down)
+		result := p.evaluate(context.Background())[0]
+		if calls := atomic.LoadInt32(fake.calls); calls != tt.expectedCalls {
+			t.Errorf("#%d: expected calls=%d, get=%d", i, tt.expectedCalls, calls)
+		}
+		if result.Result != tt.expectedResult || result.Breaker != tt.expectedState {
+			t.Errorf("#%d: expected result=%v breaker=%s, get result=%v breaker=%s", i, tt.expectedResult, tt.expectedState, result.Result, result.Breaker)
+		}
+		if !strings.HasPrefix(result.Output, tt.expectedOutput) {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, result.Output)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := newBreaker(breakerOptions{Failures: 1, CoolDown: duration(time.Nanosecond)})
+	b.record(checkResult{Result: probe.Failure, Output: "connection refused"})
+	time.Sleep(time.Millisecond)
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("expected the trial probe to be allowed")
+	}
+	ok, result := b.allow()
+	if ok || result.Breaker != breakerHalfOpen {
+		t.Errorf("expected a single trial probe, get allowed=%v breaker=%s", ok, result.Breaker)
+	}
+	if expected := "circuit breaker half-open, trial probe in progress: connection refused"; result.Output != expected {
+		t.Errorf("expected output=%q, get=%q", expected, result.Output)
+	}
+}
@@ -0,0 +1,164 @@
+package prober
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// defaultResultMaxAge is how old the last state of a check reported by its
+// endpoint may be when the config does not set it, the default period of
+// Kubernetes probes.
+const defaultResultMaxAge = 10 * time.Second
+
+// remember keeps the results, and the results of the checks of groups, as
+// the last state of their checks.
+func (p *prober) remember(results []checkResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last == nil {
+		p.last = make(map[string]checkResult)
+		p.evaluated = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for _, result := range results {
+		p.last[result.Name] = result
+		p.evaluated[result.Name] = now
+		for _, member := range result.Members {
+			p.last[member.Name] = member
+			p.evaluated[member.Name] = now
+		}
+	}
+}
+
+// resultMaxAge returns how old the last state of a check may be.
+func (p *prober) resultMaxAge() time.Duration {
+	if p.config.ResultMaxAge > 0 {
+		return time.Duration(p.config.ResultMaxAge)
+	}
+	return defaultResultMaxAge
+}
+
+// lastResults returns the last state of every check and group in config
+// order. Checks not evaluated yet are unknown.
+func (p *prober) lastResults() []checkResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var results []checkResult
+	add := func(name, kind, severity string) {
+		result, ok := p.last[name]
+		if !ok {
+			result = checkResult{Name: name, Type: kind, Severity: severity, Source: p.config.sources[name], Result: probe.Unknown, Output: "not evaluated yet"}
+		}
+		results = append(results, result)
+	}
+	for _, c := range p.checks() {
+		add(c.name, c.kind, c.severity())
+	}
+	for _, g := range p.config.Groups {
+		severity := g.Severity
+		if severity == "" {
+			severity = severityCritical
+		}
+		add(g.Name, "group", severity)
+	}
+	return results
+}
+
+// withDependencies returns the checks with the given names and the checks
+// they depend on, in config order.
+func withDependencies(checks []check, names []string) []check {
+	byName := make(map[string]check, len(checks))
+	for _, c := range checks {
+		byName[c.name] = c
+	}
+	needed := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if needed[name] {
+			return
+		}
+		needed[name] = true
+		for _, dependency := range byName[name].options.DependsOn {
+			visit(dependency)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	var selected []check
+	for _, c := range checks {
+		if needed[c.name] {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// listChecks lists every check with its last state, without probing.
+func (p *prober) listChecks(w http.ResponseWriter, r *http.Request) {
+	results := p.lastResults()
+	if f := newTagFilter(r); !f.empty() {
+		results = filterResults(results, p.selected(f))
+	}
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+	var buf bytes.Buffer
+	for _, result := range results {
+		fmt.Fprintf(&buf, "%s %s %s", result.Name, result.Type, result.Result)
+		if result.Source != "" {
+			fmt.Fprintf(&buf, " %s", result.Source)
+		}
+		buf.WriteString("\n")
+	}
+	w.Write(buf.Bytes())
+}
+
+// singleCheck reports the status of the check, or group, named in the
+// path as liveness does. It reports the last state of the check unless it
+// was never evaluated, is older than the result max age or ?refresh=true is
+// given.
+func (p *prober) singleCheck(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/checks/")
+	var members []string
+	for _, c := range p.checks() {
+		if c.name == name {
+			members = []string{name}
+		}
+	}
+	for _, g := range p.config.Groups {
+		if g.Name == name {
+			members = g.Checks
+		}
+	}
+	if members == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	p.mu.Lock()
+	result, ok := p.last[name]
+	evaluated := p.evaluated[name]
+	p.mu.Unlock()
+	if !ok || time.Since(evaluated) > p.resultMaxAge() || r.URL.Query().Get("refresh") == "true" {
+		results := p.evaluateChecks(r.Context(), withDependencies(p.checks(), members))
+		for _, evaluated := range results {
+			if evaluated.Name == name {
+				result = evaluated
+			}
+			for _, member := range evaluated.Members {
+				if member.Name == name {
+					result = member
+				}
+			}
+		}
+	}
+	status, errMsgs := p.summarize([]checkResult{result})
+	p.respond(w, r, status, errMsgs, []checkResult{result})
+}
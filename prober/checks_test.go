@@ -0,0 +1,128 @@
+package prober
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestWithDependencies(t *testing.T) {
+	checks := []check{
+		{name: "network"},
+		{name: "db", options: checkOptions{DependsOn: []string{"network"}}},
+		{name: "cache"},
+		{name: "api", options: checkOptions{DependsOn: []string{"db"}}},
+	}
+	tests := []struct {
+		names    []string
+		expected []string
+	}{
+		{[]string{"cache"}, []string{"cache"}},
+		{[]string{"api"}, []string{"network", "db", "api"}},
+		{[]string{"api", "cache"}, []string{"network", "db", "cache", "api"}},
+	}
+	for i, tt := range tests {
+		var names []string
+		for _, c := range withDependencies(checks, tt.names) {
+			names = append(names, c.name)
+		}
+		if len(names) != len(tt.expected) {
+			t.Errorf("#%d: expected checks=%v, get=%v", i, tt.expected, names)
+			continue
+		}
+		for j := range names {
+			if names[j] != tt.expected[j] {
+				t.Errorf("#%d: expected checks=%v, get=%v", i, tt.expected, names)
+				break
+			}
+		}
+	}
+}
+
+func TestSingleCheckResultMaxAge(t *testing.T) {
+	down := map[string]bool{"10.0.0.1": true}
+	p := newProber(&probeConfig{
+		ResultMaxAge: duration(50 * time.Millisecond),
+		Service:      service{TCP: []tcpService{{Name: "cassandra", IP: "10.0.0.1"}}},
+	})
+	p.tcpProber = fakeHostTCPProber{down}
+	tests := []struct {
+		down         bool
+		wait         time.Duration
+		expectedCode int
+	}{
+		{true, 0, http.StatusServiceUnavailable},
+		// The last state is reported while it is fresh, then the check is
+		// probed again.
+		{false, 0, http.StatusServiceUnavailable},
+		{false, 100 * time.Millisecond, http.StatusOK},
+	}
+	for i, tt := range tests {
+		down["10.0.0.1"] = tt.down
+		time.Sleep(tt.wait)
+		w := httptest.NewRecorder()
+		p.singleCheck(w, httptest.NewRequest("GET", "/checks/cassandra", nil))
+		if w.Code != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, w.Code)
+		}
+	}
+}
+
+func TestChecksEndpoints(t *testing.T) {
+	down := map[string]bool{"10.0.0.2": true}
+	p := newProber(&probeConfig{
+		Service: service{
+			TCP: []tcpService{
+				{Name: "cassandra-1", IP: "10.0.0.1"},
+				{Name: "cassandra-2", IP: "10.0.0.2"},
+				{Name: "mongo", IP: "10.0.0.3", checkOptions: checkOptions{DependsOn: []string{"cassandra-1"}}},
+			},
+		},
+		Groups: []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1", "cassandra-2"}, Policy: policyAny}},
+	})
+	p.tcpProber = fakeHostTCPProber{down}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/checks", p.listChecks)
+	mux.HandleFunc("/checks/", p.singleCheck)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	tests := []struct {
+		down           bool
+		path           string
+		expectedCode   int
+		expectedResult string
+	}{
+		{true, "/checks", http.StatusOK, "cassandra-1 tcp unknown\ncassandra-2 tcp unknown\nmongo tcp unknown\ncassandra group unknown\n"},
+		{true, "/checks/cassandra-2", http.StatusServiceUnavailable, "cassandra-2 connection refused\n\n"},
+		{true, "/checks/mongo", http.StatusOK, "OK"},
+		{true, "/checks", http.StatusOK, "cassandra-1 tcp success\ncassandra-2 tcp failure\nmongo tcp success\ncassandra group unknown\n"},
+		// The last state is reported until a refresh is asked for or it is
+		// older than the result max age.
+		{false, "/checks/cassandra-2", http.StatusServiceUnavailable, "cassandra-2 connection refused\n\n"},
+		{false, "/checks/cassandra-2?refresh=true", http.StatusOK, "OK"},
+		{true, "/checks/cassandra", http.StatusOK, "OK"},
+		{true, "/checks/unknown", http.StatusNotFound, "404 page not found\n"},
+	}
+	for i, tt := range tests {
+		down["10.0.0.2"] = tt.down
+		res, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, res.StatusCode)
+		}
+		if string(body) != tt.expectedResult {
+			t.Errorf("#%d: expected result=%q, get=%q", i, tt.expectedResult, body)
+		}
+	}
+	if result := p.lastResults()[3]; result.Name != "cassandra" || result.Result != probe.Success {
+		t.Errorf("expected cassandra result=%v, get %s result=%v", probe.Success, result.Name, result.Result)
+	}
+}
@@ -0,0 +1,130 @@
+package prober
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// configFiles returns the config files of the directory in name order.
+func configFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json", "*.toml", "*.hcl"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// configFile is a file of the config directory.
+type configFile struct {
+	name       string
+	configType string
+	data       []byte
+}
+
+// readConfigFiles reads the config files of the directory in name order.
+func readConfigFiles(dir string) ([]configFile, error) {
+	names, err := configFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: no config file", dir)
+	}
+	var files []configFile
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		file := probeConfig{}
+		if err := file.getConfigType(name); err != nil {
+			return nil, err
+		}
+		files = append(files, configFile{name: name, configType: file.configType, data: data})
+	}
+	return files, nil
+}
+
+// mergeConfigFiles merges the config files into the config. The checks and
+// groups of the files are appended, and the settings they set override
+// those read before. The merged config is validated as a whole, so that
+// files may depend on the checks of each other.
+func (c *probeConfig) mergeConfigFiles(files []configFile) error {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	for _, file := range files {
+		fragment := probeConfig{configType: file.configType, templates: c.templates}
+		if err := fragment.unmarshal(file.data); err != nil {
+			return fmt.Errorf("%s: %v", file.name, err)
+		}
+		if err := c.merge(fragment, filepath.Base(file.name)); err != nil {
+			return err
+		}
+	}
+	return c.validate()
+}
+
+// merge appends the checks and groups of the fragment read from file, and
+// takes the settings it sets.
+func (c *probeConfig) merge(fragment probeConfig, file string) error {
+	defined := make(map[string]bool)
+	for _, name := range c.names() {
+		defined[name] = true
+	}
+	for _, name := range fragment.names() {
+		if !defined[name] {
+			c.sources[name] = file
+			continue
+		}
+		other := c.sources[name]
+		if other == "" {
+			other = "the config file"
+		}
+		return fmt.Errorf("%s: duplicate check name in %s and %s", name, other, file)
+	}
+
+	services := reflect.ValueOf(&c.Service).Elem()
+	additions := reflect.ValueOf(fragment.Service)
+	for i := 0; i < services.NumField(); i++ {
+		services.Field(i).Set(reflect.AppendSlice(services.Field(i), additions.Field(i)))
+	}
+	c.Groups = append(c.Groups, fragment.Groups...)
+
+	if fragment.Concurrency != 0 {
+		c.Concurrency = fragment.Concurrency
+	}
+	if fragment.Deadline != 0 {
+		c.Deadline = fragment.Deadline
+	}
+	if fragment.InitialDelay != 0 {
+		c.InitialDelay = fragment.InitialDelay
+	}
+	if fragment.StartupTimeout != 0 {
+		c.StartupTimeout = fragment.StartupTimeout
+	}
+	if fragment.ResultMaxAge != 0 {
+		c.ResultMaxAge = fragment.ResultMaxAge
+	}
+	return nil
+}
+
+// names returns the names of the checks and groups of the config.
+func (c *probeConfig) names() []string {
+	var names []string
+	for _, check := range (&prober{config: *c}).checks() {
+		names = append(names, check.name)
+	}
+	for _, g := range c.Groups {
+		names = append(names, g.Name)
+	}
+	return names
+}
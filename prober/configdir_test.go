@@ -0,0 +1,162 @@
+package prober
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadConfigDir(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"db.yaml": `
+concurrency: 4
+service:
+  tcp:
+  - name: casandra
+    ip: 127.0.0.1
+    port: 9042
+    dependsOn: [network]
+groups:
+- name: storage
+  checks: [casandra, mongo]
+`,
+		"network.yml": `
+service:
+  tcp:
+  - name: network
+    ip: 10.0.0.1
+`,
+		"web.json":  `{"service": {"http": [{"name": "mongo", "url": "http://127.0.0.1:27017"}]}}`,
+		"README.md": "not a config",
+	})
+	defer os.RemoveAll(dir)
+
+	c := probeConfig{}
+	if err := c.readConfig(newConfigSource(""), dir); err != nil {
+		t.Fatal(err)
+	}
+	expectedSources := map[string]string{"casandra": "db.yaml", "storage": "db.yaml", "network": "network.yml", "mongo": "web.json"}
+	if !reflect.DeepEqual(c.sources, expectedSources) {
+		t.Errorf("expected sources=%v, get=%v", expectedSources, c.sources)
+	}
+	if c.Concurrency != 4 || len(c.Service.TCP) != 2 || len(c.Service.HTTP) != 1 || len(c.Groups) != 1 {
+		t.Errorf("expected the merged config, get=%+v", c)
+	}
+
+	p := newProber(&c)
+	p.tcpProber = fakeHostTCPProber{}
+	p.httpProber = fakeHTTPProber{}
+	for _, result := range p.evaluate(context.Background()) {
+		if result.Source != expectedSources[result.Name] {
+			t.Errorf("%s: expected source=%s, get=%s", result.Name, expectedSources[result.Name], result.Source)
+		}
+	}
+}
+
+func TestReadConfigDirErrors(t *testing.T) {
+	tests := []struct {
+		base          string
+		files         map[string]string
+		expectedError string
+	}{
+		{"", map[string]string{}, "no config file"},
+		{"", map[string]string{
+			"a.yaml": "service:\n  tcp:\n  - name: casandra\n",
+			"b.json": `{"service": {"http": [{"name": "casandra"}]}}`,
+		}, "casandra: duplicate check name in a.yaml and b.json"},
+		{"service:\n  tcp:\n  - name: casandra\n", map[string]string{
+			"a.yaml": "service:\n  tcp:\n  - name: casandra\n",
+		}, "casandra: duplicate check name in the config file and a.yaml"},
+		{"", map[string]string{
+			"a.yaml": "service:\n  tcp:\n  - name: casandra\n    dependsOn: [network]\n",
+		}, "casandra: unknown dependency network"},
+		{"", map[string]string{
+			"a.yaml": "service:\n  tcp:\n  - name: casandra\n    extends: internal\n",
+		}, "casandra: unknown template internal"},
+		{"templates:\n  internal: {severity: non-critical}\n", map[string]string{
+			"a.json": `{"templates": {"internal": {"severity": "critical"}}}`,
+		}, "template internal is defined in the config file and a.json"},
+	}
+	for i, tt := range tests {
+		dir := writeConfigDir(t, tt.files)
+		source := newConfigSource("")
+		if tt.base != "" {
+			source = newConfigSource(dir + ".yaml")
+			ioutil.WriteFile(source.name, []byte(tt.base), 0600)
+		}
+		c := probeConfig{}
+		err := c.readConfig(source, dir)
+		os.RemoveAll(dir)
+		os.Remove(dir + ".yaml")
+		// Errors about the directory start with its path.
+		if err == nil || !strings.HasSuffix(err.Error(), tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+// The defaults and templates of every file apply to the checks of all of
+// them.
+func TestReadConfigDirTemplates(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"00-templates.yaml": `
+defaults:
+  tcp:
+    port: 9042
+templates:
+  internal:
+    severity: non-critical
+    timeout: 3s
+`,
+		"db.yaml": `
+service:
+  tcp:
+  - name: casandra
+    ip: 127.0.0.1
+    extends: internal
+`,
+		"web.toml": `
+[[service.http]]
+name = "mongo"
+url = "http://127.0.0.1:27017"
+extends = "base"
+`,
+	})
+	defer os.RemoveAll(dir)
+	base := dir + ".json"
+	ioutil.WriteFile(base, []byte(`{"defaults": {"http": {"timeout": "5s"}}, "templates": {"base": {"retries": 2}}, "service": {"tcp": [{"name": "network", "ip": "10.0.0.1"}]}}`), 0600)
+	defer os.Remove(base)
+
+	c := probeConfig{}
+	if err := c.readConfig(newConfigSource(base), dir); err != nil {
+		t.Fatal(err)
+	}
+	network, casandra, mongo := c.Service.TCP[0], c.Service.TCP[1], c.Service.HTTP[0]
+	if network.Port != 9042 {
+		t.Errorf("expected network to take the defaults of 00-templates.yaml, get=%+v", network)
+	}
+	if casandra.Port != 9042 || casandra.Severity != severityNonCritical || casandra.TimeOut != duration(3*time.Second) {
+		t.Errorf("expected casandra to extend internal of 00-templates.yaml, get=%+v", casandra)
+	}
+	if mongo.TimeOut != duration(5*time.Second) || mongo.Retries != 2 {
+		t.Errorf("expected mongo to take the defaults and extend base of the config file, get=%+v", mongo)
+	}
+}
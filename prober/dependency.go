@@ -0,0 +1,81 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// skipped is the result of a check whose dependency did not succeed.
+const skipped probe.Result = "skipped"
+
+// validateDependencies checks that dependencies name existing checks and do
+// not form a cycle.
+func validateDependencies(checks []check) error {
+	byName := make(map[string]check)
+	for _, c := range checks {
+		byName[c.name] = c
+	}
+	for _, c := range checks {
+		for _, dependency := range c.options.DependsOn {
+			if _, ok := byName[dependency]; !ok {
+				return fmt.Errorf("%s: unknown dependency %s", c.name, dependency)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i := range path {
+				if path[i] == name {
+					return errors.New("dependency cycle: " + strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range byName[name].options.DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, c := range checks {
+		if err := visit(c.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip returns the result of a check whose dependency did not succeed,
+// naming the check that caused it.
+func skip(dependency checkResult) checkResult {
+	result := checkResult{Result: skipped}
+	if dependency.Result == skipped {
+		// Report the root cause rather than the chain of skipped checks.
+		result.Output = dependency.Output
+	} else {
+		result.Output = fmt.Sprintf("skipped, dependency %s %s", dependency.Name, dependency.Result)
+		if dependency.Output != "" {
+			result.Output += ": " + dependency.Output
+		}
+	}
+	return result
+}
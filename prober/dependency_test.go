@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	dependsOn := func(names ...string) checkOptions {
+		return checkOptions{DependsOn: names}
+	}
+	tests := []struct {
+		tcp           []tcpService
+		expectedError error
+	}{
+		{[]tcpService{
+			{Name: "a"},
+			{Name: "b", checkOptions: dependsOn("a")},
+			{Name: "c", checkOptions: dependsOn("a", "b")},
+		}, nil},
+		{[]tcpService{
+			{Name: "a", checkOptions: dependsOn("d")},
+		}, errors.New("a: unknown dependency d")},
+		{[]tcpService{
+			{Name: "a", checkOptions: dependsOn("a")},
+		}, errors.New("dependency cycle: a -> a")},
+		{[]tcpService{
+			{Name: "a", checkOptions: dependsOn("c")},
+			{Name: "b", checkOptions: dependsOn("a")},
+			{Name: "c", checkOptions: dependsOn("b")},
+		}, errors.New("dependency cycle: a -> c -> b -> a")},
+		{[]tcpService{
+			{Name: "a"},
+			{Name: "b", checkOptions: dependsOn("a", "c")},
+			{Name: "c", checkOptions: dependsOn("d")},
+			{Name: "d", checkOptions: dependsOn("c")},
+		}, errors.New("dependency cycle: c -> d -> c")},
+	}
+	for i, tt := range tests {
+		c := probeConfig{Service: service{TCP: tt.tcp}}
+		err := c.validateChecks()
+		if (err == nil) != (tt.expectedError == nil) || (err != nil && err.Error() != tt.expectedError.Error()) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+func TestEvaluateDependencies(t *testing.T) {
+	p := &prober{
+		tcpProber:  fakeHostTCPProber{map[string]bool{"gateway": true}},
+		httpProber: fakeHTTPProber{result: probe.Success},
+		config: probeConfig{
+			Service: service{
+				TCP: []tcpService{
+					{Name: "network", IP: "gateway"},
+					{Name: "cassandra", IP: "cassandra", checkOptions: checkOptions{DependsOn: []string{"network"}}},
+					{Name: "local", IP: "localhost"},
+				},
+				HTTP: []httpService{
+					{Name: "api", checkOptions: checkOptions{DependsOn: []string{"local", "cassandra"}}},
+					{Name: "ui", checkOptions: checkOptions{DependsOn: []string{"local"}}},
+				},
+			},
+		},
+	}
+	expected := map[string]checkResult{
+		"network":   {Result: probe.Failure, Output: "connection refused"},
+		"cassandra": {Result: skipped, Output: "skipped, dependency network failure: connection refused"},
+		"local":     {Result: probe.Success},
+		"api":       {Result: skipped, Output: "skipped, dependency network failure: connection refused"},
+		"ui":        {Result: probe.Success, Output: "message"},
+	}
+	results := p.evaluate(context.Background())
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, get %d", len(expected), len(results))
+	}
+	for _, result := range results {
+		e := expected[result.Name]
+		if result.Result != e.Result || result.Output != e.Output {
+			t.Errorf("%s: expected result=%v output=%q, get result=%v output=%q", result.Name, e.Result, e.Output, result.Result, result.Output)
+		}
+	}
+}
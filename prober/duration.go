@@ -0,0 +1,78 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// duration is a config duration. It is written as a Go duration string like
+// "15s" or "500ms", or as a number of seconds, the same way in YAML and
+// JSON.
+//
+// Configs used to write durations as numbers of nanoseconds. Numbers of
+// seconds of maxSeconds or more are rejected, so that such a config fails to
+// load instead of having its 1s timeout, 1000000000, read as 31 years. They
+// are to be written as duration strings, which the error suggests.
+type duration time.Duration
+
+// maxSeconds bounds the durations written as numbers of seconds, about 11
+// days. Every former duration of 1ms or more in nanoseconds exceeds it.
+const maxSeconds = 1e6
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+// parseDuration parses a duration string, or a number of seconds.
+func parseDuration(s string) (duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.Abs(seconds) > math.MaxInt64/float64(time.Second) {
+			return 0, errors.New("duration " + s + " is out of range")
+		}
+		if math.Abs(seconds) >= maxSeconds {
+			return 0, errors.New("duration " + s + " is too long as a number of seconds, durations are no longer numbers of nanoseconds: write " +
+				strconv.Quote(time.Duration(seconds).String()) + " if nanoseconds were meant, or a duration string")
+		}
+		return duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("invalid duration " + strconv.Quote(s) + `, expected a duration like "15s" or a number of seconds`)
+	}
+	return duration(d), nil
+}
+
+func (d *duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	var err error
+	*d, err = parseDuration(s)
+	return err
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return errors.New("invalid duration " + string(data) + `, expected a duration like "15s" or a number of seconds`)
+		}
+		s = number.String()
+	}
+	var err error
+	*d, err = parseDuration(s)
+	return err
+}
+
+func (d duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
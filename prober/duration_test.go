@@ -0,0 +1,113 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		yaml          string
+		json          string
+		expected      duration
+		expectedError error
+	}{
+		{`15s`, `"15s"`, duration(15 * time.Second), nil},
+		{`500ms`, `"500ms"`, duration(500 * time.Millisecond), nil},
+		{`1m30s`, `"1m30s"`, duration(90 * time.Second), nil},
+		{`15`, `15`, duration(15 * time.Second), nil},
+		{`"15"`, `"15"`, duration(15 * time.Second), nil},
+		{`0.25`, `0.25`, duration(250 * time.Millisecond), nil},
+		{`fast`, `"fast"`, 0, errors.New(`invalid duration "fast", expected a duration like "15s" or a number of seconds`)},
+		{`1e300`, `1e300`, 0, errors.New("duration 1e300 is out of range")},
+		{`999999`, `999999`, duration(999999 * time.Second), nil},
+		{`1000000000`, `1000000000`, 0, errors.New(`duration 1000000000 is too long as a number of seconds, durations are no longer numbers of nanoseconds: write "1s" if nanoseconds were meant, or a duration string`)},
+		{`"1000000000"`, `"1000000000"`, 0, errors.New(`duration 1000000000 is too long as a number of seconds, durations are no longer numbers of nanoseconds: write "1s" if nanoseconds were meant, or a duration string`)},
+		{`277777h46m40s`, `"277777h46m40s"`, duration(1000000000 * time.Second), nil},
+	}
+	for i, tt := range tests {
+		var fromYAML, fromJSON struct{ TimeOut duration }
+		yamlErr := yaml.Unmarshal([]byte("timeout: "+tt.yaml), &fromYAML)
+		jsonErr := json.Unmarshal([]byte(`{"timeout": `+tt.json+`}`), &fromJSON)
+		if fromYAML.TimeOut != tt.expected || fromJSON.TimeOut != tt.expected {
+			t.Errorf("#%d: expected duration=%v, get yaml=%v json=%v", i, tt.expected, fromYAML.TimeOut, fromJSON.TimeOut)
+		}
+		if !reflect.DeepEqual(yamlErr, tt.expectedError) || !reflect.DeepEqual(jsonErr, tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get yaml=%v json=%v", i, tt.expectedError, yamlErr, jsonErr)
+		}
+	}
+}
+
+func TestConfigFormatsEquivalent(t *testing.T) {
+	var fromYAML, fromJSON probeConfig
+	if err := fromYAML.readConfig(newConfigSource("../test/config.yaml"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromJSON.readConfig(newConfigSource("../test/config.json"), ""); err != nil {
+		t.Fatal(err)
+	}
+	fromJSON.configType = fromYAML.configType
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("expected the yaml and json configs to be equal, get yaml=%+v json=%+v", fromYAML, fromJSON)
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	config := probeConfig{
+		Concurrency:  4,
+		Deadline:     duration(900 * time.Millisecond),
+		InitialDelay: duration(10 * time.Second),
+		Service: service{
+			TCP: []tcpService{{Name: "casandra", IP: "127.0.0.1", Port: 9042, TimeOut: duration(15 * time.Second)}},
+			HTTP: []httpService{{
+				Name:    "mongo",
+				URL:     configString{value: "http://127.0.0.1:27017"},
+				Header:  []httpHeader{{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Muffins"}}},
+				TimeOut: duration(1500 * time.Millisecond),
+				checkOptions: checkOptions{
+					Severity:     severityNonCritical,
+					DependsOn:    []string{"casandra"},
+					InitialDelay: durationPtr(time.Minute),
+					Flap:         &flapOptions{Window: 5, HighThreshold: 50, Hold: true},
+					Breaker:      &breakerOptions{Failures: 3, CoolDown: duration(30 * time.Second)},
+					Retries:      2,
+					Backoff:      &backoffOptions{Policy: backoffExponential, Interval: duration(100 * time.Millisecond), Jitter: 0.1},
+					Tags:         []string{"db"},
+				},
+			}},
+			File: []fileService{{Name: "ready", Path: "/tmp/ready", MaxAge: duration(time.Hour)}},
+		},
+		Groups: []checkGroup{{Name: "storage", Checks: []string{"casandra", "mongo"}, Policy: policyAny}},
+	}
+	formats := []struct {
+		configType string
+		marshal    func(interface{}) ([]byte, error)
+	}{
+		{"yaml", yaml.Marshal},
+		{"json", json.Marshal},
+	}
+	// Slices written empty are read back empty rather than nil, so configs
+	// are compared in their written form.
+	for _, format := range formats {
+		data, err := format.marshal(config)
+		if err != nil {
+			t.Fatalf("%s: %v", format.configType, err)
+		}
+		c := probeConfig{configType: format.configType}
+		if err := c.convertDataToStruct(data); err != nil {
+			t.Fatalf("%s: %v", format.configType, err)
+		}
+		again, err := format.marshal(c)
+		if err != nil {
+			t.Fatalf("%s: %v", format.configType, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("%s: expected config=\n%s\nget=\n%s", format.configType, data, again)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Defaults of the flap detection.
+const (
+	defaultFlapWindow    = 10
+	defaultFlapThreshold = 50
+)
+
+// flapOptions enables flap detection for a check. A check starts flapping
+// when the percentage of state changes over its last results reaches
+// highThreshold, and stops when it falls below lowThreshold.
+type flapOptions struct {
+	// Window is the number of recent results considered.
+	Window        int
+	HighThreshold float64 `json:"highThreshold" yaml:"highThreshold"`
+	// LowThreshold defaults to highThreshold.
+	LowThreshold float64 `json:"lowThreshold" yaml:"lowThreshold"`
+	// Hold reports the last stable result while the check is flapping.
+	Hold bool
+}
+
+func (o flapOptions) validate(name string) error {
+	if o.Window != 0 && o.Window < 3 {
+		return errors.New(name + ": flap window must be at least 3")
+	}
+	if o.HighThreshold < 0 || o.HighThreshold > 100 || o.LowThreshold < 0 || o.LowThreshold > 100 {
+		return errors.New(name + ": flap thresholds must be between 0 and 100")
+	}
+	if o.LowThreshold > o.HighThreshold && o.HighThreshold != 0 {
+		return errors.New(name + ": flap lowThreshold must not exceed highThreshold")
+	}
+	return nil
+}
+
+// flapDetector keeps the recent results of a check.
+type flapDetector struct {
+	mu       sync.Mutex
+	options  flapOptions
+	history  []bool
+	flapping bool
+	stable   *checkResult
+}
+
+func newFlapDetector(options flapOptions) *flapDetector {
+	if options.Window == 0 {
+		options.Window = defaultFlapWindow
+	}
+	if options.HighThreshold == 0 {
+		options.HighThreshold = defaultFlapThreshold
+	}
+	if options.LowThreshold == 0 {
+		options.LowThreshold = options.HighThreshold
+	}
+	return &flapDetector{options: options}
+}
+
+// record adds the result to the history and returns it marked as flapping
+// when it is, holding the last stable result if asked to. Pending and
+// skipped results say nothing about the check and are left out.
+func (d *flapDetector) record(result checkResult) checkResult {
+	if result.Result == pending || result.Result == skipped {
+		return result
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	passed := result.Result == probe.Success || result.Result == warning
+	d.history = append(d.history, passed)
+	if len(d.history) > d.options.Window {
+		d.history = d.history[1:]
+	}
+	changes := 0
+	for i := 1; i < len(d.history); i++ {
+		if d.history[i] != d.history[i-1] {
+			changes++
+		}
+	}
+	percent := 0.0
+	if len(d.history) == d.options.Window {
+		percent = float64(changes) / float64(d.options.Window-1) * 100
+	}
+	switch {
+	case !d.flapping && percent >= d.options.HighThreshold:
+		d.flapping = true
+	case d.flapping && percent < d.options.LowThreshold:
+		d.flapping = false
+	}
+	result.StateChange = &percent
+	if !d.flapping {
+		// A state is stable once it is seen twice in a row.
+		if n := len(d.history); d.stable == nil || n > 1 && d.history[n-1] == d.history[n-2] {
+			stable := result
+			d.stable = &stable
+		}
+		return result
+	}
+	result.Flapping = true
+	if d.options.Hold && d.stable != nil {
+		result.Output = fmt.Sprintf("flapping (%.0f%% state changes), holding %s: %s", percent, d.stable.Result, result.Output)
+		result.Result = d.stable.Result
+		result.err = d.stable.err
+		result.Error = d.stable.Error
+	} else {
+		result.Output = fmt.Sprintf("flapping (%.0f%% state changes): %s", percent, result.Output)
+	}
+	return result
+}
+
+// detectFlaps records the result with the flap detector of its check, if
+// any.
+func (p *prober) detectFlaps(result checkResult) checkResult {
+	if d, ok := p.flaps[result.Name]; ok {
+		return d.record(result)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package prober
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestFlapOptions(t *testing.T) {
+	var service tcpService
+	err := yaml.Unmarshal([]byte(`
+name: mongo
+flap:
+  window: 5
+  highThreshold: 50
+  lowThreshold: 25
+  hold: true
+`), &service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &flapOptions{Window: 5, HighThreshold: 50, LowThreshold: 25, Hold: true}
+	if !reflect.DeepEqual(service.Flap, expected) {
+		t.Errorf("expected flap=%+v, get=%+v", expected, service.Flap)
+	}
+
+	tests := []struct {
+		options       flapOptions
+		expectedError error
+	}{
+		{flapOptions{}, nil},
+		{flapOptions{Window: 2}, errors.New("mongo: flap window must be at least 3")},
+		{flapOptions{HighThreshold: 150}, errors.New("mongo: flap thresholds must be between 0 and 100")},
+		{flapOptions{HighThreshold: 20, LowThreshold: 40}, errors.New("mongo: flap lowThreshold must not exceed highThreshold")},
+	}
+	for i, tt := range tests {
+		err := tt.options.validate("mongo")
+		if !reflect.DeepEqual(err, tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+func TestFlapDetector(t *testing.T) {
+	up := checkResult{Result: probe.Success}
+	down := checkResult{Result: probe.Failure, Output: "connection refused"}
+	tests := []struct {
+		hold             bool
+		results          []checkResult
+		expectedFlapping []bool
+		expectedResult   probe.Result
+		expectedOutput   string
+	}{
+		// A single failure is not flapping.
+		{false, []checkResult{up, up, up, up, down}, []bool{false, false, false, false, false}, probe.Failure, "connection refused"},
+		{false, []checkResult{up, down, up, down, up, down}, []bool{false, false, false, false, true, true}, probe.Failure, "flapping (100% state changes): connection refused"},
+		{true, []checkResult{up, down, up, down, up, down}, []bool{false, false, false, false, true, true}, probe.Success, "flapping (100% state changes), holding success: connection refused"},
+		// Flapping stops below the low threshold only.
+		{false, []checkResult{up, down, up, down, up, up, up, up}, []bool{false, false, false, false, true, true, true, false}, probe.Success, ""},
+	}
+	for i, tt := range tests {
+		d := newFlapDetector(flapOptions{Window: 5, HighThreshold: 75, LowThreshold: 40, Hold: tt.hold})
+		var result checkResult
+		for j, r := range tt.results {
+			result = d.record(r)
+			if result.Flapping != tt.expectedFlapping[j] {
+				t.Errorf("#%d: result %d: expected flapping=%v, get=%v", i, j, tt.expectedFlapping[j], result.Flapping)
+			}
+		}
+		if result.Result != tt.expectedResult || result.Output != tt.expectedOutput {
+			t.Errorf("#%d: expected result=%v output=%q, get result=%v output=%q", i, tt.expectedResult, tt.expectedOutput, result.Result, result.Output)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package prober
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+)
+
+// toJSON converts a toml or hcl config to json, so that every format
+// shares the schema and the decoding of the json config.
+func toJSON(configType string, configFile []byte) ([]byte, error) {
+	var doc interface{}
+	switch configType {
+	case "toml":
+		if _, err := toml.Decode(string(configFile), &doc); err != nil {
+			return nil, err
+		}
+	case "hcl":
+		if err := hcl.Unmarshal(configFile, &doc); err != nil {
+			return nil, err
+		}
+		doc = shapeHCLConfig(doc)
+	}
+	return json.Marshal(doc)
+}
+
+// shapeHCLConfig reshapes the config decoded from hcl, where every block is
+// a list of objects, after the config structs. The defaults take the fields
+// of their check type, and templates those of any check type.
+func shapeHCLConfig(doc interface{}) interface{} {
+	root, ok := hclObject(doc)
+	if !ok {
+		return doc
+	}
+	for key, value := range root {
+		switch strings.ToLower(key) {
+		case "defaults":
+			if defaults, ok := hclObject(value); ok {
+				for kind, fields := range defaults {
+					defaults[kind] = shapeHCL(fields, checkType(kind))
+				}
+				value = defaults
+			}
+		case "templates":
+			if templates, ok := hclObject(value); ok {
+				for name, fields := range templates {
+					if fields, ok := hclObject(fields); ok {
+						for field, value := range fields {
+							_, ft := templateField(field)
+							fields[field] = shapeHCL(value, ft)
+						}
+						templates[name] = fields
+					}
+				}
+				value = templates
+			}
+		default:
+			value = shapeHCL(value, fieldType(reflect.TypeOf(probeConfig{}), key))
+		}
+		root[key] = value
+	}
+	return root
+}
+
+// shapeHCL reshapes the value decoded from hcl after the type t, unwrapping
+// the blocks given for objects. Values of unknown type are left as is.
+func shapeHCL(value interface{}, t reflect.Type) interface{} {
+	if t == nil {
+		return value
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(configString{}):
+		if object, ok := hclObject(value); ok {
+			return object
+		}
+	case t.Kind() == reflect.Struct:
+		if object, ok := hclObject(value); ok {
+			for key, value := range object {
+				object[key] = shapeHCL(value, fieldType(t, key))
+			}
+			return object
+		}
+	case t.Kind() == reflect.Map:
+		if object, ok := hclObject(value); ok {
+			for key, value := range object {
+				object[key] = shapeHCL(value, t.Elem())
+			}
+			return object
+		}
+	case t.Kind() == reflect.Slice:
+		if list, ok := hclList(value); ok {
+			for i, value := range list {
+				list[i] = shapeHCL(value, t.Elem())
+			}
+			return list
+		}
+	}
+	return value
+}
+
+// hclObject returns the value as an object, merging the blocks hcl decodes
+// into a list.
+func hclObject(value interface{}) (map[string]interface{}, bool) {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object, true
+	}
+	list, ok := hclList(value)
+	if !ok {
+		return nil, false
+	}
+	object := make(map[string]interface{})
+	for _, item := range list {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		for key, value := range fields {
+			object[key] = value
+		}
+	}
+	return object, true
+}
+
+func hclList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// fieldType returns the type of the field of the struct t the config key
+// names, looking into embedded structs, or nil when there is none.
+func fieldType(t reflect.Type, key string) reflect.Type {
+	_, ft := configField(t, key)
+	return ft
+}
+
+// configField returns the config key and the type of the field of the
+// struct t the key names regardless of case, as JSON decoding matches them.
+// The config key is the json tag of the field, or its lowercased name.
+func configField(t reflect.Type, key string) (string, reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if name, ft := configField(field.Type, key); ft != nil {
+				return name, ft
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.ToLower(field.Name)
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+			name = tag
+		}
+		if strings.EqualFold(name, key) {
+			return name, field.Type
+		}
+	}
+	return "", nil
+}
+
+// checkType returns the type of the checks of the kind, or nil when the
+// kind is unknown.
+func checkType(kind string) reflect.Type {
+	if t := fieldType(reflect.TypeOf(service{}), kind); t != nil {
+		return t.Elem()
+	}
+	return nil
+}
+
+// templateField returns the config key and the type of the field in the
+// first check type that has it.
+func templateField(key string) (string, reflect.Type) {
+	t := reflect.TypeOf(service{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, ft := configField(t.Field(i).Type.Elem(), key); ft != nil {
+			return name, ft
+		}
+	}
+	return "", nil
+}
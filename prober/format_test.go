@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHCLTemplates(t *testing.T) {
+	urlFile, err := ioutil.TempFile("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(urlFile.Name())
+	urlFile.WriteString("http://127.0.0.1:8080\n")
+	urlFile.Close()
+
+	configFile := `
+defaults {
+  http {
+    timeout = "5s"
+    header {
+      name = "Authorization"
+      value = "token"
+    }
+  }
+}
+
+templates "internal" {
+  severity = "non-critical"
+  dependsOn = ["casandra"]
+  flap {
+    window = 20
+  }
+}
+
+service {
+  tcp {
+    name = "casandra"
+    ip = "127.0.0.1"
+    port = 9042
+  }
+
+  http {
+    name = "api"
+    url = { fromFile = "` + urlFile.Name() + `" }
+    extends = "internal"
+  }
+}
+`
+	c := probeConfig{configType: "hcl"}
+	if err := c.unmarshal([]byte(configFile)); err != nil {
+		t.Fatal(err)
+	}
+	api := c.Service.HTTP[0]
+	expectedHeader := []httpHeader{{Name: "Authorization", Value: configString{value: "token"}}}
+	if !reflect.DeepEqual(api.Header, expectedHeader) || api.TimeOut != duration(5*time.Second) {
+		t.Errorf("expected api to take the http defaults, get=%+v", api)
+	}
+	if api.Severity != severityNonCritical || !reflect.DeepEqual(api.DependsOn, []string{"casandra"}) || api.Flap == nil || api.Flap.Window != 20 {
+		t.Errorf("expected api to extend internal, get=%+v", api.checkOptions)
+	}
+	if api.URL.value != "http://127.0.0.1:8080" {
+		t.Errorf("expected the url read from its file, get=%v", api.URL.value)
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tests := []struct {
+		configType string
+		configFile string
+	}{
+		{"toml", "concurrency = \n"},
+		{"hcl", "service {\n"},
+	}
+	for i, tt := range tests {
+		c := probeConfig{configType: tt.configType}
+		if err := c.unmarshal([]byte(tt.configFile)); err == nil {
+			t.Errorf("#%d: expected a %s syntax error, get=nil", i, tt.configType)
+		}
+	}
+}
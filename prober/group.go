@@ -0,0 +1,190 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Group policies, a group may instead set atLeast or percent.
+const (
+	policyAll = "all"
+	policyAny = "any"
+)
+
+// checkGroup bundles checks that are reported as a single check, which
+// succeeds when enough of its checks succeed.
+type checkGroup struct {
+	Name         string
+	Checks       []string
+	Policy       string
+	AtLeast      int `json:"atLeast" yaml:"atLeast"`
+	Percent      float64
+	checkOptions `yaml:",inline"`
+}
+
+// required returns how many checks of the group must succeed.
+func (g checkGroup) required() int {
+	switch {
+	case g.AtLeast > 0:
+		return g.AtLeast
+	case g.Percent > 0:
+		return int(math.Ceil(g.Percent / 100 * float64(len(g.Checks))))
+	case g.Policy == policyAny:
+		return 1
+	}
+	return len(g.Checks)
+}
+
+// validateGroups checks that groups only bundle existing checks, and that
+// a check belongs to at most one group.
+func (c *probeConfig) validateGroups(names map[string]bool) error {
+	grouped := make(map[string]string)
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return errors.New("group name is empty")
+		}
+		if names[g.Name] {
+			return errors.New(g.Name + ": duplicate check name")
+		}
+		names[g.Name] = true
+		if len(g.Checks) == 0 {
+			return errors.New(g.Name + ": group checks is empty")
+		}
+		policies := 0
+		if g.Policy != "" {
+			if g.Policy != policyAll && g.Policy != policyAny {
+				return errors.New(g.Name + ": group policy must be " + policyAll + " or " + policyAny)
+			}
+			policies++
+		}
+		if g.AtLeast != 0 {
+			if g.AtLeast < 0 || g.AtLeast > len(g.Checks) {
+				return fmt.Errorf("%s: group atLeast must be between 1 and %d", g.Name, len(g.Checks))
+			}
+			policies++
+		}
+		if g.Percent != 0 {
+			if g.Percent < 0 || g.Percent > 100 {
+				return errors.New(g.Name + ": group percent must be between 0 and 100")
+			}
+			policies++
+		}
+		if policies > 1 {
+			return errors.New(g.Name + ": group needs only one of policy, atLeast and percent")
+		}
+		switch g.Severity {
+		case "", severityCritical, severityNonCritical:
+		default:
+			return errors.New(g.Name + ": severity must be " + severityCritical + " or " + severityNonCritical)
+		}
+		if g.Flap != nil {
+			if err := g.Flap.validate(g.Name); err != nil {
+				return err
+			}
+		}
+		if g.Breaker != nil {
+			return errors.New(g.Name + ": groups have no breaker, set it on their checks")
+		}
+		if g.Retries != 0 || g.Backoff != nil {
+			return errors.New(g.Name + ": groups have no retries, set them on their checks")
+		}
+		for _, name := range g.Checks {
+			if other, ok := grouped[name]; ok {
+				return fmt.Errorf("%s: check %s is already in group %s", g.Name, name, other)
+			}
+			if !names[name] || name == g.Name {
+				return fmt.Errorf("%s: unknown check %s", g.Name, name)
+			}
+			grouped[name] = g.Name
+		}
+	}
+	return nil
+}
+
+// evaluate folds the results of the checks of the group into one result.
+func (g checkGroup) evaluate(members []checkResult) checkResult {
+	result := checkResult{
+		Name:     g.Name,
+		Type:     "group",
+		Severity: g.Severity,
+		Members:  members,
+	}
+	if result.Severity == "" {
+		result.Severity = severityCritical
+	}
+	passed := 0
+	var failed []string
+	for _, member := range members {
+		if member.Result == probe.Success || member.Result == warning || member.Result == pending {
+			passed++
+		} else {
+			failed = append(failed, member.Name)
+		}
+		if member.Duration > result.Duration {
+			result.Duration = member.Duration
+		}
+	}
+	required := g.required()
+	result.Output = fmt.Sprintf("%d/%d checks succeeded, %d required", passed, len(members), required)
+	if len(failed) > 0 {
+		result.Output += ", failed: " + strings.Join(failed, ", ")
+	}
+	result.Result = probe.Success
+	if passed < required {
+		result.Result = probe.Failure
+	}
+	return result
+}
+
+// applyGroups replaces the results of grouped checks with the results of
+// their groups, which take the place of their first check. Groups missing
+// some of their checks in results are left out.
+func (p *prober) applyGroups(results []checkResult) []checkResult {
+	if len(p.config.Groups) == 0 {
+		return results
+	}
+	byName := make(map[string]checkResult)
+	for _, result := range results {
+		byName[result.Name] = result
+	}
+	groupOf := make(map[string]int)
+	for i, g := range p.config.Groups {
+		complete := true
+		for _, name := range g.Checks {
+			_, ok := byName[name]
+			complete = complete && ok
+		}
+		if !complete {
+			continue
+		}
+		for _, name := range g.Checks {
+			groupOf[name] = i
+		}
+	}
+	applied := make([]checkResult, 0, len(results))
+	reported := make(map[int]bool)
+	for _, result := range results {
+		i, ok := groupOf[result.Name]
+		if !ok {
+			applied = append(applied, result)
+			continue
+		}
+		if reported[i] {
+			continue
+		}
+		reported[i] = true
+		g := p.config.Groups[i]
+		members := make([]checkResult, 0, len(g.Checks))
+		for _, name := range g.Checks {
+			members = append(members, byName[name])
+		}
+		result := g.evaluate(members)
+		result.Source = p.config.sources[g.Name]
+		applied = append(applied, p.detectFlaps(result))
+	}
+	return applied
+}
@@ -0,0 +1,112 @@
+package prober
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestGroupRequired(t *testing.T) {
+	checks := []string{"a", "b", "c"}
+	tests := []struct {
+		group    checkGroup
+		expected int
+	}{
+		{checkGroup{Checks: checks}, 3},
+		{checkGroup{Checks: checks, Policy: policyAll}, 3},
+		{checkGroup{Checks: checks, Policy: policyAny}, 1},
+		{checkGroup{Checks: checks, AtLeast: 2}, 2},
+		{checkGroup{Checks: checks, Percent: 50}, 2},
+		{checkGroup{Checks: checks, Percent: 100}, 3},
+	}
+	for i, tt := range tests {
+		if required := tt.group.required(); required != tt.expected {
+			t.Errorf("#%d: expected required=%d, get=%d", i, tt.expected, required)
+		}
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	tcp := []tcpService{{Name: "cassandra-1"}, {Name: "cassandra-2"}, {Name: "cassandra-3"}}
+	tests := []struct {
+		tcp           []tcpService
+		groups        []checkGroup
+		expectedError error
+	}{
+		{tcp, []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1", "cassandra-2", "cassandra-3"}, AtLeast: 2}}, nil},
+		{append(tcp, tcpService{Name: "cassandra-1"}), nil, errors.New("cassandra-1: duplicate check name")},
+		{tcp, []checkGroup{{Name: "cassandra-1", Checks: []string{"cassandra-2"}}}, errors.New("cassandra-1: duplicate check name")},
+		{tcp, []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-4"}}}, errors.New("cassandra: unknown check cassandra-4")},
+		{tcp, []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1"}, AtLeast: 2}}, errors.New("cassandra: group atLeast must be between 1 and 1")},
+		{tcp, []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1"}, Policy: "most"}}, errors.New("cassandra: group policy must be all or any")},
+		{tcp, []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1"}, Policy: "any", Percent: 50}}, errors.New("cassandra: group needs only one of policy, atLeast and percent")},
+		{tcp, []checkGroup{
+			{Name: "a", Checks: []string{"cassandra-1"}},
+			{Name: "b", Checks: []string{"cassandra-1"}},
+		}, errors.New("b: check cassandra-1 is already in group a")},
+	}
+	for i, tt := range tests {
+		c := probeConfig{Service: service{TCP: tt.tcp}, Groups: tt.groups}
+		err := c.validateChecks()
+		if (err == nil) != (tt.expectedError == nil) || (err != nil && err.Error() != tt.expectedError.Error()) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+// fakeHostTCPProber fails the hosts it is given.
+type fakeHostTCPProber struct {
+	down map[string]bool
+}
+
+func (p fakeHostTCPProber) Probe(host string, port int, timeout time.Duration) (probe.Result, string, error) {
+	if p.down[host] {
+		return probe.Failure, "connection refused", nil
+	}
+	return probe.Success, "", nil
+}
+
+func TestLivenessGroup(t *testing.T) {
+	config := probeConfig{
+		Service: service{
+			TCP: []tcpService{
+				{Name: "cassandra-1", IP: "10.0.0.1"},
+				{Name: "cassandra-2", IP: "10.0.0.2"},
+				{Name: "cassandra-3", IP: "10.0.0.3"},
+			},
+		},
+		Groups: []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1", "cassandra-2", "cassandra-3"}, AtLeast: 2}},
+	}
+	tests := []struct {
+		down           map[string]bool
+		expectedCode   int
+		expectedResult string
+	}{
+		{map[string]bool{}, http.StatusOK, "OK"},
+		{map[string]bool{"10.0.0.2": true}, http.StatusOK, "OK"},
+		{map[string]bool{"10.0.0.2": true, "10.0.0.3": true}, http.StatusServiceUnavailable,
+			"cassandra 1/3 checks succeeded, 2 required, failed: cassandra-2, cassandra-3\n\n"},
+	}
+	for i, tt := range tests {
+		p := &prober{tcpProber: fakeHostTCPProber{tt.down}, config: config}
+		ts := httptest.NewServer(http.HandlerFunc(p.liveness))
+		res, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Close()
+		if res.StatusCode != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, res.StatusCode)
+		}
+		if string(body) != tt.expectedResult {
+			t.Errorf("#%d: expected result=%q, get=%q", i, tt.expectedResult, body)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// configString is a config value that may reference environment
+// variables, as ${VAR} or ${VAR:-default}, or be read from a file with
+// {fromFile: path}. References are resolved when the config is loaded, and
+// the values they resolve to are redacted when printed.
+type configString struct {
+	value    string
+	redacted bool
+}
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expand substitutes the environment variables referenced in s.
+func (v *configString) expand(s string) error {
+	var err error
+	v.value = envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		match := envReference.FindStringSubmatch(reference)
+		v.redacted = true
+		value, ok := os.LookupEnv(match[1])
+		if match[2] != "" {
+			// Like the shell, the default also replaces an empty value.
+			if value == "" {
+				return match[3]
+			}
+			return value
+		}
+		if !ok && err == nil {
+			err = errors.New("environment variable " + match[1] + " is not set")
+		}
+		return value
+	})
+	return err
+}
+
+// readFile sets the value to the content of the file, without its trailing
+// newline.
+func (v *configString) readFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v.value = strings.TrimRight(string(content), "\r\n")
+	v.redacted = true
+	return nil
+}
+
+type fileReference struct {
+	FromFile string `json:"fromFile" yaml:"fromFile"`
+}
+
+func (v *configString) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		return v.expand(s)
+	}
+	var reference fileReference
+	if err := unmarshal(&reference); err != nil {
+		return err
+	}
+	return v.readFile(reference.FromFile)
+}
+
+func (v *configString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return v.expand(s)
+	}
+	var reference fileReference
+	if err := json.Unmarshal(data, &reference); err != nil {
+		return err
+	}
+	return v.readFile(reference.FromFile)
+}
+
+// MarshalYAML writes the resolved value.
+func (v configString) MarshalYAML() (interface{}, error) {
+	return v.value, nil
+}
+
+// MarshalJSON writes the resolved value.
+func (v configString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+// Format prints the value, or redacted when it was resolved from the
+// environment or a file.
+func (v configString) Format(f fmt.State, verb rune) {
+	if v.redacted {
+		fmt.Fprint(f, redacted)
+		return
+	}
+	fmt.Fprint(f, v.value)
+}
+
+// secrets returns the values of the config resolved from the environment or
+// files.
+func (c *probeConfig) secrets() []string {
+	var values []string
+	add := func(v configString) {
+		if v.redacted && v.value != "" {
+			values = append(values, v.value)
+		}
+	}
+	for _, config := range c.Service.HTTP {
+		add(config.URL)
+		for _, header := range config.Header {
+			add(header.Value)
+		}
+	}
+	for _, config := range c.Service.WebSocket {
+		add(config.URL)
+		for _, header := range config.Header {
+			add(header.Value)
+		}
+	}
+	for _, config := range c.Service.Etcd {
+		for _, endpoint := range config.Endpoints {
+			add(endpoint)
+		}
+		add(config.CA)
+		add(config.Cert)
+		add(config.Key)
+	}
+	for _, config := range c.Service.FTP {
+		add(config.Password)
+	}
+	return values
+}
+
+// redact replaces the resolved values of the config in s.
+func (p *prober) redact(s string) string {
+	if p.redactor == nil {
+		return s
+	}
+	return p.redactor.Replace(s)
+}
+
+// redactResult redacts the secrets of the config from what the result
+// reports, which the probers build from the configured urls and headers.
+func (p *prober) redactResult(result checkResult) checkResult {
+	if p.redactor == nil {
+		return result
+	}
+	result.Output = p.redact(result.Output)
+	result.Error = p.redact(result.Error)
+	if result.err != nil {
+		result.err = errors.New(p.redact(result.err.Error()))
+	}
+	if result.AttemptErrors != nil {
+		attemptErrors := make([]string, len(result.AttemptErrors))
+		for i, attemptError := range result.AttemptErrors {
+			attemptErrors[i] = p.redact(attemptError)
+		}
+		result.AttemptErrors = attemptErrors
+	}
+	return result
+}
+
+func newRedactor(secrets []string) *strings.Replacer {
+	if len(secrets) == 0 {
+		return nil
+	}
+	var pairs []string
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, redacted)
+	}
+	return strings.NewReplacer(pairs...)
+}
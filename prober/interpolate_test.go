@@ -0,0 +1,154 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestConfigStringExpand(t *testing.T) {
+	os.Setenv("SERVICE_PROBER_TOKEN", "s3cr3t")
+	defer os.Unsetenv("SERVICE_PROBER_TOKEN")
+	tests := []struct {
+		value         string
+		expected      configString
+		expectedError error
+	}{
+		{"Muffins", configString{value: "Muffins"}, nil},
+		{"Bearer ${SERVICE_PROBER_TOKEN}", configString{value: "Bearer s3cr3t", redacted: true}, nil},
+		{"${SERVICE_PROBER_UNSET:-guest}", configString{value: "guest", redacted: true}, nil},
+		{"${SERVICE_PROBER_UNSET:-}", configString{value: "", redacted: true}, nil},
+		{"${SERVICE_PROBER_TOKEN:-guest}", configString{value: "s3cr3t", redacted: true}, nil},
+		{"${SERVICE_PROBER_UNSET}", configString{value: "", redacted: true}, errors.New("environment variable SERVICE_PROBER_UNSET is not set")},
+		{"$SERVICE_PROBER_TOKEN", configString{value: "$SERVICE_PROBER_TOKEN"}, nil},
+	}
+	for i, tt := range tests {
+		var v configString
+		err := v.expand(tt.value)
+		if v != tt.expected {
+			t.Errorf("#%d: expected value=%+v, get=%+v", i, tt.expected, v)
+		}
+		if !reflect.DeepEqual(err, tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+func TestConfigStringFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "password")
+	ioutil.WriteFile(path, []byte("hunter2\n"), 0600)
+	os.Setenv("SERVICE_PROBER_USER", "admin")
+	defer os.Unsetenv("SERVICE_PROBER_USER")
+
+	yamlConfig := []byte(`
+url: ftp://${SERVICE_PROBER_USER}@127.0.0.1
+password:
+  fromFile: ` + path + `
+`)
+	jsonConfig := []byte(`{"url": "ftp://${SERVICE_PROBER_USER}@127.0.0.1", "password": {"fromFile": "` + path + `"}}`)
+	expected := struct{ URL, Password configString }{
+		URL:      configString{value: "ftp://admin@127.0.0.1", redacted: true},
+		Password: configString{value: "hunter2", redacted: true},
+	}
+	for i, unmarshal := range []func() (interface{}, error){
+		func() (interface{}, error) {
+			var v struct{ URL, Password configString }
+			err := yaml.Unmarshal(yamlConfig, &v)
+			return v, err
+		},
+		func() (interface{}, error) {
+			var v struct{ URL, Password configString }
+			err := json.Unmarshal(jsonConfig, &v)
+			return v, err
+		},
+	} {
+		v, err := unmarshal()
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Errorf("#%d: expected config=%+v, get=%+v", i, expected, v)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	config := probeConfig{
+		Service: service{
+			HTTP: []httpService{{
+				Name:   "api",
+				URL:    configString{value: "http://127.0.0.1/?token=s3cr3t", redacted: true},
+				Header: []httpHeader{{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Muffins"}}},
+			}},
+			Etcd: []etcdService{{
+				Name:      "etcd",
+				Endpoints: []configString{{value: "https://etcd.internal:2379", redacted: true}},
+				CA:        configString{value: "/etc/tls/ca.pem", redacted: true},
+			}},
+			FTP: []ftpService{{Name: "ftp", Password: configString{value: "hunter2", redacted: true}}},
+		},
+	}
+	dump := fmt.Sprintf("%+v", config)
+	for _, expected := range []string{"URL:[redacted]", "Value:Muffins", "Password:[redacted]"} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("expected dump to contain %s, get=%s", expected, dump)
+		}
+	}
+	p := newProber(&config)
+	if got := p.redact("api Get http://127.0.0.1/?token=s3cr3t: connection refused, hunter2"); got != "api Get [redacted]: connection refused, [redacted]" {
+		t.Errorf("expected secrets to be redacted, get=%s", got)
+	}
+	if got := p.redact("Get https://etcd.internal:2379/health: open /etc/tls/ca.pem"); got != "Get [redacted]/health: open [redacted]" {
+		t.Errorf("expected etcd endpoints and tls files to be redacted, get=%s", got)
+	}
+}
+
+func TestRedactResponses(t *testing.T) {
+	p := newProber(&probeConfig{
+		Service: service{
+			HTTP: []httpService{{
+				Name:         "api",
+				URL:          configString{value: "http://127.0.0.1:1/?token=s3cr3t", redacted: true},
+				TimeOut:      duration(time.Second),
+				checkOptions: checkOptions{Retries: 1, Backoff: &backoffOptions{Interval: duration(time.Millisecond)}},
+			}},
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/liveness", p.liveness)
+	mux.HandleFunc("/checks", p.listChecks)
+	mux.HandleFunc("/checks/", p.singleCheck)
+	mux.HandleFunc("/metrics", p.metrics)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	for i, path := range []string{"/liveness", "/liveness?format=json", "/checks?format=json", "/checks/api", "/checks/api?format=json", "/metrics"} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if strings.Contains(string(body), "s3cr3t") {
+			t.Errorf("#%d: expected the secret redacted from %s, get=%s", i, path, body)
+		}
+		if i == 0 && !strings.Contains(string(body), "[redacted]") {
+			t.Errorf("#%d: expected the url redacted in %s, get=%s", i, path, body)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package prober
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// metric is a single sample of the Prometheus text exposition format.
+type metric struct {
+	labels []string
+	value  float64
+}
+
+// metricFamily is a group of samples sharing a name.
+type metricFamily struct {
+	name    string
+	help    string
+	metrics []metric
+}
+
+func (f metricFamily) write(buf *bytes.Buffer) {
+	if len(f.metrics) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(buf, "# TYPE %s gauge\n", f.name)
+	for _, m := range f.metrics {
+		buf.WriteString(f.name)
+		if len(m.labels) > 0 {
+			var pairs []string
+			for i := 0; i+1 < len(m.labels); i += 2 {
+				pairs = append(pairs, m.labels[i]+`="`+escapeLabel(m.labels[i+1])+`"`)
+			}
+			buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+		}
+		buf.WriteString(" " + strconv.FormatFloat(m.value, 'g', -1, 64) + "\n")
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// metrics evaluates every check and exposes the results in the Prometheus
+// text format.
+func (p *prober) metrics(w http.ResponseWriter, r *http.Request) {
+	results := p.evaluateRequest(r)
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(renderMetrics(results))
+}
+
+func renderMetrics(results []checkResult) []byte {
+	success := metricFamily{name: "service_prober_check_success", help: "Whether the check succeeded."}
+	duration := metricFamily{name: "service_prober_check_duration_seconds", help: "How long the check took."}
+	pluginStatus := metricFamily{name: "service_prober_nagios_status", help: "Status of the nagios plugin, 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN."}
+	perfdata := metricFamily{name: "service_prober_nagios_perfdata", help: "Performance data reported by the nagios plugin."}
+	flapping := metricFamily{name: "service_prober_check_flapping", help: "Whether the check is flapping."}
+	stateChange := metricFamily{name: "service_prober_check_state_change_percent", help: "Percentage of state changes over the recent results of the check."}
+	for _, result := range results {
+		labels := []string{"name", result.Name, "type", result.Type}
+		value := 0.0
+		if result.Result == probe.Success {
+			value = 1
+		}
+		success.metrics = append(success.metrics, metric{labels, value})
+		duration.metrics = append(duration.metrics, metric{labels, result.Duration})
+		if result.PluginStatus != nil {
+			pluginStatus.metrics = append(pluginStatus.metrics, metric{[]string{"name", result.Name}, float64(*result.PluginStatus)})
+		}
+		if result.StateChange != nil {
+			value := 0.0
+			if result.Flapping {
+				value = 1
+			}
+			flapping.metrics = append(flapping.metrics, metric{labels, value})
+			stateChange.metrics = append(stateChange.metrics, metric{labels, *result.StateChange})
+		}
+		for _, p := range result.Perfdata {
+			perfdata.metrics = append(perfdata.metrics, metric{[]string{"name", result.Name, "label", p.Label, "uom", p.UOM}, p.Value})
+		}
+	}
+	var buf bytes.Buffer
+	for _, family := range []metricFamily{success, duration, flapping, stateChange, pluginStatus, perfdata} {
+		family.write(&buf)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,46 @@
+package prober
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/probe"
+
+	nagiosprobe "github.com/tony24681379/service-prober/probe/nagios"
+)
+
+func TestRenderMetrics(t *testing.T) {
+	warning := nagiosprobe.Warning
+	stateChange := 60.0
+	results := []checkResult{
+		{Name: "casandra", Type: "tcp", Result: probe.Success, Duration: 0.5},
+		{Name: "load", Type: "nagios", Result: probe.Success, Duration: 0.25, PluginStatus: &warning,
+			Perfdata: []nagiosprobe.Perfdata{{Label: `disk "/"`, Value: 42, UOM: "%"}}},
+		{Name: "mongo", Type: "http", Result: probe.Failure, Duration: 1, Flapping: true, StateChange: &stateChange},
+	}
+	expected := `# HELP service_prober_check_success Whether the check succeeded.
+# TYPE service_prober_check_success gauge
+service_prober_check_success{name="casandra",type="tcp"} 1
+service_prober_check_success{name="load",type="nagios"} 1
+service_prober_check_success{name="mongo",type="http"} 0
+# HELP service_prober_check_duration_seconds How long the check took.
+# TYPE service_prober_check_duration_seconds gauge
+service_prober_check_duration_seconds{name="casandra",type="tcp"} 0.5
+service_prober_check_duration_seconds{name="load",type="nagios"} 0.25
+service_prober_check_duration_seconds{name="mongo",type="http"} 1
+# HELP service_prober_check_flapping Whether the check is flapping.
+# TYPE service_prober_check_flapping gauge
+service_prober_check_flapping{name="mongo",type="http"} 1
+# HELP service_prober_check_state_change_percent Percentage of state changes over the recent results of the check.
+# TYPE service_prober_check_state_change_percent gauge
+service_prober_check_state_change_percent{name="mongo",type="http"} 60
+# HELP service_prober_nagios_status Status of the nagios plugin, 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN.
+# TYPE service_prober_nagios_status gauge
+service_prober_nagios_status{name="load"} 1
+# HELP service_prober_nagios_perfdata Performance data reported by the nagios plugin.
+# TYPE service_prober_nagios_perfdata gauge
+service_prober_nagios_perfdata{name="load",label="disk \"/\"",uom="%"} 42
+`
+	if got := string(renderMetrics(results)); got != expected {
+		t.Errorf("expected metrics=\n%s\nget=\n%s", expected, got)
+	}
+}
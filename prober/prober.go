@@ -1,11 +1,15 @@
 package prober
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -17,49 +21,300 @@ import (
 	//	execprobe "k8s.io/kubernetes/pkg/probe/exec"
 	httprobe "k8s.io/kubernetes/pkg/probe/http"
 	tcprobe "k8s.io/kubernetes/pkg/probe/tcp"
+
+	diskprobe "github.com/tony24681379/service-prober/probe/disk"
+	etcdprobe "github.com/tony24681379/service-prober/probe/etcd"
+	fileprobe "github.com/tony24681379/service-prober/probe/file"
+	ftpprobe "github.com/tony24681379/service-prober/probe/ftp"
+	imapprobe "github.com/tony24681379/service-prober/probe/imap"
+	nagiosprobe "github.com/tony24681379/service-prober/probe/nagios"
+	processprobe "github.com/tony24681379/service-prober/probe/process"
+	smtpprobe "github.com/tony24681379/service-prober/probe/smtp"
+	sshprobe "github.com/tony24681379/service-prober/probe/ssh"
+	wsprobe "github.com/tony24681379/service-prober/probe/websocket"
+	zkprobe "github.com/tony24681379/service-prober/probe/zookeeper"
 )
 
 type probeConfig struct {
 	configType string
-	Service    service
+	// sources maps the checks and groups to the files of the config
+	// directory they are defined in.
+	sources map[string]string
+	// templates holds the defaults and templates of the files of the
+	// config, when it is read from several.
+	templates *configTemplates
+	// Concurrency limits how many checks run at once, 0 means no limit.
+	Concurrency int
+	// Deadline bounds a whole evaluation, checks that have not finished by
+	// then are reported as timeout.
+	Deadline duration
+	// InitialDelay is how long after the prober started checks are first
+	// probed, they are pending until then.
+	InitialDelay duration `json:"initialDelay" yaml:"initialDelay"`
+	// StartupTimeout enables the startup mode, where liveness succeeds
+	// until every check has passed once or the timeout expires.
+	StartupTimeout duration `json:"startupTimeout" yaml:"startupTimeout"`
+	// ResultMaxAge is how old the last state of a check reported by its
+	// endpoint may be before the check is probed again, 10s when unset.
+	ResultMaxAge duration `json:"resultMaxAge" yaml:"resultMaxAge"`
+	Service      service
+	Groups       []checkGroup
 }
 
 type service struct {
 	//	Exec []execService
-	TCP  []tcpService
-	HTTP []httpService
+	TCP       []tcpService
+	HTTP      []httpService
+	Etcd      []etcdService
+	ZooKeeper []zookeeperService
+	SMTP      []smtpService
+	IMAP      []imapService
+	FTP       []ftpService
+	SSH       []sshService
+	WebSocket []webSocketService
+	File      []fileService
+	Disk      []diskService
+	Process   []processService
+	Nagios    []nagiosService
+}
+
+// checkOptions are the options shared by every type of check.
+type checkOptions struct {
+	// Severity is critical, the default, or non-critical. A failing
+	// non-critical check degrades the prober without failing it.
+	Severity string
+	// DependsOn names the checks that must succeed for this check to be
+	// evaluated, it is skipped otherwise.
+	DependsOn []string `json:"dependsOn" yaml:"dependsOn"`
+	// InitialDelay overrides the global initial delay when it is set, to 0
+	// to probe the check right away.
+	InitialDelay *duration `json:"initialDelay,omitempty" yaml:"initialDelay,omitempty"`
+	// Flap enables flap detection.
+	Flap *flapOptions
+	// Breaker enables a circuit breaker.
+	Breaker *breakerOptions
+	// Retries is how many more times a failing check is probed within its
+	// timeout, waiting as set by Backoff between attempts.
+	Retries int
+	Backoff *backoffOptions
+	// Tags select the check with the tag and exclude query parameters of
+	// the endpoints.
+	Tags []string
 }
 
 type execService struct {
 	Name    string
 	Cmd     []string
-	TimeOut time.Duration
+	TimeOut duration
 }
 
 type tcpService struct {
-	Name    string
-	IP      string
-	Port    int
-	TimeOut time.Duration
+	Name         string
+	IP           string
+	Port         int
+	TimeOut      duration
+	checkOptions `yaml:",inline"`
 }
 
 type httpService struct {
-	Name    string
-	URL     string
-	Header  []httpHeader
-	TimeOut time.Duration
+	Name         string
+	URL          configString
+	Header       []httpHeader
+	TimeOut      duration
+	checkOptions `yaml:",inline"`
 }
 
 type httpHeader struct {
 	Name  string
-	Value string
+	Value configString
+}
+
+type etcdService struct {
+	Name            string
+	Endpoints       []configString
+	MaxRaftIndexLag uint64 `json:"maxRaftIndexLag" yaml:"maxRaftIndexLag"`
+	// CA, Cert and Key are PEM files of the CA that signed the members
+	// certificates and of the client certificate, for https endpoints.
+	CA                 configString
+	Cert               configString
+	Key                configString
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	TimeOut            duration
+	checkOptions       `yaml:",inline"`
+}
+
+// tlsConfig returns the TLS config of the check, reading its files.
+func (s etcdService) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: s.InsecureSkipVerify}
+	if s.CA.value != "" {
+		pem, err := ioutil.ReadFile(s.CA.value)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate in etcd ca %v", s.Name, s.CA)
+		}
+	}
+	if (s.Cert.value == "") != (s.Key.value == "") {
+		return nil, errors.New(s.Name + ": etcd cert and key must be set together")
+	}
+	if s.Cert.value != "" {
+		cert, err := tls.LoadX509KeyPair(s.Cert.value, s.Key.value)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+type zookeeperService struct {
+	Name                   string
+	Servers                []string
+	MaxOutstandingRequests int `json:"maxOutstandingRequests" yaml:"maxOutstandingRequests"`
+	TimeOut                duration
+	checkOptions           `yaml:",inline"`
+}
+
+type smtpService struct {
+	Name               string
+	Host               string
+	Port               int
+	StartTLS           bool `json:"startTLS" yaml:"startTLS"`
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	TimeOut            duration
+	checkOptions       `yaml:",inline"`
+}
+
+type imapService struct {
+	Name               string
+	Host               string
+	Port               int
+	TLS                bool
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	TimeOut            duration
+	checkOptions       `yaml:",inline"`
+}
+
+type ftpService struct {
+	Name         string
+	Host         string
+	Port         int
+	User         string
+	Password     configString
+	TimeOut      duration
+	checkOptions `yaml:",inline"`
+}
+
+type sshService struct {
+	Name             string
+	Host             string
+	Port             int
+	HostKeyAlgorithm string `json:"hostKeyAlgorithm" yaml:"hostKeyAlgorithm"`
+	Fingerprint      string
+	TimeOut          duration
+	checkOptions     `yaml:",inline"`
+}
+
+type webSocketService struct {
+	Name               string
+	URL                configString
+	Header             []httpHeader
+	Subprotocol        string
+	Send               string
+	Ping               bool
+	Expect             string
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+	TimeOut            duration
+	checkOptions       `yaml:",inline"`
+}
+
+type fileService struct {
+	Name         string
+	Path         string
+	Readable     bool
+	Writable     bool
+	MaxAge       duration `json:"maxAge" yaml:"maxAge"`
+	checkOptions `yaml:",inline"`
+}
+
+type diskService struct {
+	Name                 string
+	Path                 string
+	MinFreePercent       float64 `json:"minFreePercent" yaml:"minFreePercent"`
+	MinFreeBytes         uint64  `json:"minFreeBytes" yaml:"minFreeBytes"`
+	MinFreeInodesPercent float64 `json:"minFreeInodesPercent" yaml:"minFreeInodesPercent"`
+	MinFreeInodes        uint64  `json:"minFreeInodes" yaml:"minFreeInodes"`
+	checkOptions         `yaml:",inline"`
+}
+
+type processService struct {
+	Name         string
+	Command      string
+	Cmdline      string
+	PIDFile      string `json:"pidFile" yaml:"pidFile"`
+	MaxRSS       uint64 `json:"maxRSS" yaml:"maxRSS"`
+	MaxOpenFiles int    `json:"maxOpenFiles" yaml:"maxOpenFiles"`
+	checkOptions `yaml:",inline"`
+}
+
+type nagiosService struct {
+	Name         string
+	Cmd          []string
+	TimeOut      duration
+	checkOptions `yaml:",inline"`
 }
 
 type prober struct {
 	//	exec       execprobe.ExecProber
-	httpProber httprobe.HTTPProber
-	tcpProber  tcprobe.TCPProber
-	config     probeConfig
+	httpProber      httprobe.HTTPProber
+	tcpProber       tcprobe.TCPProber
+	etcdProber      etcdprobe.EtcdProber
+	zookeeperProber zkprobe.ZooKeeperProber
+	smtpProber      smtpprobe.SMTPProber
+	imapProber      imapprobe.IMAPProber
+	ftpProber       ftpprobe.FTPProber
+	sshProber       sshprobe.SSHProber
+	webSocketProber wsprobe.WebSocketProber
+	fileProber      fileprobe.FileProber
+	diskProber      diskprobe.DiskProber
+	processProber   processprobe.ProcessProber
+	nagiosProber    nagiosprobe.NagiosProber
+	config          probeConfig
+	// slots bounds the number of checks running at once across requests,
+	// it is nil when concurrency is not limited.
+	slots    chan struct{}
+	started  time.Time
+	startup  *startup
+	flaps    map[string]*flapDetector
+	breakers map[string]*breaker
+	// redactor replaces the secrets of the config in logs.
+	redactor *strings.Replacer
+	// last holds the last result of every check and group, and
+	// evaluated when it was evaluated.
+	mu        sync.Mutex
+	last      map[string]checkResult
+	evaluated map[string]time.Time
+}
+
+// check is a configured service bound to the prober that evaluates it.
+type check struct {
+	name string
+	kind string
+	// timeout bounds all the attempts of the check, 0 means no timeout.
+	timeout time.Duration
+	options checkOptions
+	// probe runs a single attempt within timeLeft.
+	probe func(timeLeft time.Duration) checkResult
+}
+
+// severity returns the severity of the check, critical by default.
+func (c check) severity() string {
+	if c.options.Severity == "" {
+		return severityCritical
+	}
+	return c.options.Severity
 }
 
 func (c *probeConfig) getConfigType(configFileName string) error {
@@ -72,64 +327,259 @@ func (c *probeConfig) getConfigType(configFileName string) error {
 	if result == "yml" {
 		result = "yaml"
 	}
-	if result != "yaml" && result != "json" {
-		return errors.New("please use yaml or json config file")
+	if result != "yaml" && result != "json" && result != "toml" && result != "hcl" {
+		return errors.New("please use yaml, json, toml or hcl config file")
 	}
 	c.configType = result
 	return nil
 }
 
-func (c *probeConfig) readConfig(configFileName string) error {
-	var err error
-	err = c.getConfigType(configFileName)
-	if err != nil {
-		return err
+// readConfig reads the config from the source, when it is named, merges
+// the files of configDir, when it is set, and validates the result. The
+// defaults and templates of every file apply to the checks of all of them.
+func (c *probeConfig) readConfig(source *configSource, configDir string) error {
+	var data []byte
+	if source.name != "" {
+		var err error
+		if data, _, err = source.read(); err != nil {
+			return err
+		}
 	}
+	var files []configFile
+	if configDir != "" {
+		var err error
+		if files, err = readConfigFiles(configDir); err != nil {
+			return err
+		}
+		c.templates = &configTemplates{}
+		if source.name != "" {
+			if err := c.templates.collect(source.configTypes(data), data, ""); err != nil {
+				return fmt.Errorf("%s: %v", source.name, err)
+			}
+		}
+		for _, file := range files {
+			if err := c.templates.collect([]string{file.configType}, file.data, filepath.Base(file.name)); err != nil {
+				return fmt.Errorf("%s: %v", file.name, err)
+			}
+		}
+	}
+	if source.name != "" {
+		if err := c.decodeConfig(source, data); err != nil {
+			return err
+		}
+	}
+	if configDir != "" {
+		return c.mergeConfigFiles(files)
+	}
+	return c.validate()
+}
 
-	configFile, err := ioutil.ReadFile(configFileName)
-	if err != nil {
+func (c *probeConfig) convertDataToStruct(configFile []byte) error {
+	if err := c.unmarshal(configFile); err != nil {
 		return err
 	}
-	err = c.convertDataToStruct(configFile)
+	return c.validate()
+}
+
+func (c *probeConfig) unmarshal(configFile []byte) error {
+	configType := c.configType
+	if configType == "toml" || configType == "hcl" {
+		var err error
+		if configFile, err = toJSON(configType, configFile); err != nil {
+			return err
+		}
+		configType = "json"
+	}
+	configFile, err := expandTemplates(configType, configFile, c.templates)
 	if err != nil {
 		return err
 	}
+	if configType == "yaml" {
+		return yaml.Unmarshal(configFile, &c)
+	} else if configType == "json" {
+		return json.Unmarshal(configFile, &c)
+	}
 	return nil
 }
 
-func (c *probeConfig) convertDataToStruct(configFile []byte) error {
-	var err error
-	if c.configType == "yaml" {
-		err = yaml.Unmarshal(configFile, &c)
-	} else if c.configType == "json" {
-		err = json.Unmarshal(configFile, &c)
+func (c *probeConfig) validate() error {
+	if c.Concurrency < 0 {
+		return errors.New("concurrency must not be negative")
 	}
-	if err != nil {
+	if c.Deadline < 0 {
+		return errors.New("deadline must not be negative")
+	}
+	if c.InitialDelay < 0 || c.StartupTimeout < 0 {
+		return errors.New("initialDelay and startupTimeout must not be negative")
+	}
+	if c.ResultMaxAge < 0 {
+		return errors.New("resultMaxAge must not be negative")
+	}
+	if err := c.validateChecks(); err != nil {
 		return err
 	}
 	for _, config := range c.Service.HTTP {
-		_, err := url.Parse(config.URL)
+		_, err := url.Parse(config.URL.value)
 		if err != nil {
 			return err
 		}
 	}
+	for _, config := range c.Service.Etcd {
+		if len(config.Endpoints) == 0 {
+			return errors.New(config.Name + ": etcd endpoints is empty")
+		}
+		if _, err := config.tlsConfig(); err != nil {
+			return err
+		}
+		for _, endpoint := range config.Endpoints {
+			_, err := url.Parse(endpoint.value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, config := range c.Service.ZooKeeper {
+		if len(config.Servers) == 0 {
+			return errors.New(config.Name + ": zookeeper servers is empty")
+		}
+	}
+	for _, config := range c.Service.SSH {
+		if config.HostKeyAlgorithm != "" && !contains(sshprobe.HostKeyAlgorithms, config.HostKeyAlgorithm) {
+			return errors.New(config.Name + ": unsupported ssh host key algorithm " + config.HostKeyAlgorithm)
+		}
+		if config.Fingerprint != "" && !strings.HasPrefix(config.Fingerprint, "SHA256:") {
+			return errors.New(config.Name + ": ssh fingerprint must be a SHA256 fingerprint")
+		}
+	}
+	for _, config := range c.Service.WebSocket {
+		u, err := url.Parse(config.URL.value)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "ws" && u.Scheme != "wss" {
+			return errors.New(config.Name + ": websocket url must use ws or wss")
+		}
+	}
+	for _, config := range c.Service.File {
+		if config.Path == "" {
+			return errors.New(config.Name + ": file path is empty")
+		}
+	}
+	for _, config := range c.Service.Disk {
+		if config.Path == "" {
+			return errors.New(config.Name + ": disk path is empty")
+		}
+	}
+	for _, config := range c.Service.Process {
+		if config.Command == "" && config.Cmdline == "" && config.PIDFile == "" {
+			return errors.New(config.Name + ": process needs a command, cmdline or pidFile")
+		}
+		if _, err := regexp.Compile(config.Cmdline); err != nil {
+			return err
+		}
+	}
+	for _, config := range c.Service.Nagios {
+		if len(config.Cmd) == 0 {
+			return errors.New(config.Name + ": nagios cmd is empty")
+		}
+	}
 	return nil
 }
 
-// Prober init prober
-func Prober(configFileName string, port string) error {
-	config := newConfig(configFileName)
-	p := newProber(config)
+// validateChecks validates the names and the options shared by every type
+// of check.
+func (c *probeConfig) validateChecks() error {
+	names := make(map[string]bool)
+	checks := (&prober{config: *c}).checks()
+	for _, check := range checks {
+		if check.name == "" {
+			return errors.New(check.kind + ": check name is empty")
+		}
+		if names[check.name] {
+			return errors.New(check.name + ": duplicate check name")
+		}
+		names[check.name] = true
+		switch check.options.Severity {
+		case "", severityCritical, severityNonCritical:
+		default:
+			return errors.New(check.name + ": severity must be " + severityCritical + " or " + severityNonCritical)
+		}
+		if check.options.InitialDelay != nil && *check.options.InitialDelay < 0 {
+			return errors.New(check.name + ": initialDelay must not be negative")
+		}
+		if check.options.Flap != nil {
+			if err := check.options.Flap.validate(check.name); err != nil {
+				return err
+			}
+		}
+		if check.options.Breaker != nil {
+			if err := check.options.Breaker.validate(check.name); err != nil {
+				return err
+			}
+		}
+		if check.options.Retries < 0 {
+			return errors.New(check.name + ": retries must not be negative")
+		}
+		if check.options.Backoff != nil {
+			if err := check.options.Backoff.validate(check.name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := validateDependencies(checks); err != nil {
+		return err
+	}
+	return c.validateGroups(names)
+}
+
+// Prober init prober. The config is read from a file, stdin when it is "-",
+// or an http(s) URL, which is fetched again every refreshInterval. The
+// files of configDir are merged into it.
+func Prober(configFileName string, configDir string, port string, refreshInterval time.Duration) error {
+	source := newConfigSource(configFileName)
+	h := &handler{}
+	c := &probeConfig{}
+	if err := c.readConfig(source, configDir); err != nil {
+		return err
+	}
+	h.prober = newProber(c)
 	if glog.V(2) {
-		glog.Infof("%+v", p)
+		// The config is printed on its own for its secrets to be redacted.
+		glog.Infof("%+v", h.prober.config)
+	}
+	if source.remote() && configDir == "" && refreshInterval > 0 {
+		go h.watch(source, refreshInterval)
 	}
-	p.serveHTTP(port)
+	h.serveHTTP(port)
 	return nil
 }
 
 func newProber(c *probeConfig) *prober {
 	p := &prober{
-		config: *c,
+		config:   *c,
+		started:  time.Now(),
+		redactor: newRedactor(c.secrets()),
+	}
+	p.flaps = make(map[string]*flapDetector)
+	p.breakers = make(map[string]*breaker)
+	var names []string
+	for _, check := range p.checks() {
+		names = append(names, check.name)
+		if check.options.Flap != nil {
+			p.flaps[check.name] = newFlapDetector(*check.options.Flap)
+		}
+		if check.options.Breaker != nil {
+			p.breakers[check.name] = newBreaker(*check.options.Breaker)
+		}
+	}
+	for _, g := range c.Groups {
+		if g.Flap != nil {
+			p.flaps[g.Name] = newFlapDetector(*g.Flap)
+		}
+	}
+	p.startup = newStartup(names, time.Duration(c.StartupTimeout))
+	if c.Concurrency > 0 {
+		p.slots = make(chan struct{}, c.Concurrency)
 	}
 	if len(c.Service.TCP) > 0 {
 		p.tcpProber = tcprobe.New()
@@ -137,21 +587,48 @@ func newProber(c *probeConfig) *prober {
 	if len(c.Service.HTTP) > 0 {
 		p.httpProber = httprobe.New()
 	}
-	return p
-}
-
-func newConfig(configFileName string) *probeConfig {
-	c := &probeConfig{}
-	err := c.readConfig(configFileName)
-	if err != nil {
-		glog.Fatalln(err)
-		panic(err)
+	if len(c.Service.Etcd) > 0 {
+		p.etcdProber = etcdprobe.New()
+	}
+	if len(c.Service.ZooKeeper) > 0 {
+		p.zookeeperProber = zkprobe.New()
 	}
-	return c
+	if len(c.Service.SMTP) > 0 {
+		p.smtpProber = smtpprobe.New()
+	}
+	if len(c.Service.IMAP) > 0 {
+		p.imapProber = imapprobe.New()
+	}
+	if len(c.Service.FTP) > 0 {
+		p.ftpProber = ftpprobe.New()
+	}
+	if len(c.Service.SSH) > 0 {
+		p.sshProber = sshprobe.New()
+	}
+	if len(c.Service.WebSocket) > 0 {
+		p.webSocketProber = wsprobe.New()
+	}
+	if len(c.Service.File) > 0 {
+		p.fileProber = fileprobe.New()
+	}
+	if len(c.Service.Disk) > 0 {
+		p.diskProber = diskprobe.New()
+	}
+	if len(c.Service.Process) > 0 {
+		p.processProber = processprobe.New()
+	}
+	if len(c.Service.Nagios) > 0 {
+		p.nagiosProber = nagiosprobe.New()
+	}
+	return p
 }
 
-func (p *prober) serveHTTP(port string) {
-	http.HandleFunc("/liveness", p.liveness)
+func (h *handler) serveHTTP(port string) {
+	http.HandleFunc("/liveness", h.serve((*prober).liveness))
+	http.HandleFunc("/startup", h.serve((*prober).startupProbe))
+	http.HandleFunc("/checks", h.serve((*prober).listChecks))
+	http.HandleFunc("/checks/", h.serve((*prober).singleCheck))
+	http.HandleFunc("/metrics", h.serve((*prober).metrics))
 	glog.Info("serve on port:", port)
 	glog.Fatal(http.ListenAndServe(":"+port, nil))
 }
@@ -159,60 +636,225 @@ func (p *prober) serveHTTP(port string) {
 func buildHeader(headerList []httpHeader) http.Header {
 	headers := make(http.Header)
 	for _, header := range headerList {
-		headers[header.Name] = append(headers[header.Name], header.Value)
+		headers[header.Name] = append(headers[header.Name], header.Value.value)
 	}
 	return headers
 }
 
-func (p *prober) liveness(w http.ResponseWriter, r *http.Request) {
-	ok := true
-	var (
-		errMsgs []string
-		health  probe.Result
-		output  string
-		err     error
-	)
-	var wg sync.WaitGroup
-	for _, config := range p.config.Service.TCP {
-		wg.Add(1)
-		go func(config tcpService) {
-			defer wg.Done()
-			health, output, err = p.tcpProber.Probe(config.IP, config.Port, config.TimeOut)
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
 
-			errMsg := p.handleError(config.Name, health, output, err)
+func parseEndpoints(endpoints []configString) []*url.URL {
+	urls := make([]*url.URL, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		u, _ := url.Parse(endpoint.value)
+		urls = append(urls, u)
+	}
+	return urls
+}
 
-			if errMsg != "" {
-				ok = false
-				errMsgs = append(errMsgs, errMsg)
-			}
-		}(config)
+// checks binds every configured service to its prober.
+func (p *prober) checks() []check {
+	var checks []check
+	for _, config := range p.config.Service.TCP {
+		config := config
+		checks = append(checks, check{config.Name, "tcp", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			return newResult(p.tcpProber.Probe(config.IP, config.Port, timeLeft))
+		}})
 	}
 	for _, config := range p.config.Service.HTTP {
-		wg.Add(1)
-		go func(config httpService) {
-			defer wg.Done()
-			u, _ := url.Parse(config.URL)
+		config := config
+		checks = append(checks, check{config.Name, "http", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			u, _ := url.Parse(config.URL.value)
 			header := buildHeader(config.Header)
-			health, output, err = p.httpProber.Probe(u, header, config.TimeOut)
+			return newResult(p.httpProber.Probe(u, header, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.Etcd {
+		config := config
+		checks = append(checks, check{config.Name, "etcd", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			tlsConfig, err := config.tlsConfig()
+			if err != nil {
+				return newResult(probe.Failure, err.Error(), nil)
+			}
+			return newResult(p.etcdProber.Probe(parseEndpoints(config.Endpoints), tlsConfig, config.MaxRaftIndexLag, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.ZooKeeper {
+		config := config
+		checks = append(checks, check{config.Name, "zookeeper", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			return newResult(p.zookeeperProber.Probe(config.Servers, config.MaxOutstandingRequests, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.SMTP {
+		config := config
+		checks = append(checks, check{config.Name, "smtp", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			var tlsConfig *tls.Config
+			if config.StartTLS {
+				tlsConfig = &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.InsecureSkipVerify}
+			}
+			return newResult(p.smtpProber.Probe(config.Host, config.Port, tlsConfig, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.IMAP {
+		config := config
+		checks = append(checks, check{config.Name, "imap", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			var tlsConfig *tls.Config
+			if config.TLS {
+				tlsConfig = &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.InsecureSkipVerify}
+			}
+			return newResult(p.imapProber.Probe(config.Host, config.Port, tlsConfig, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.FTP {
+		config := config
+		checks = append(checks, check{config.Name, "ftp", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			return newResult(p.ftpProber.Probe(config.Host, config.Port, config.User, config.Password.value, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.SSH {
+		config := config
+		checks = append(checks, check{config.Name, "ssh", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			return newResult(p.sshProber.Probe(config.Host, config.Port, config.HostKeyAlgorithm, config.Fingerprint, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.WebSocket {
+		config := config
+		checks = append(checks, check{config.Name, "websocket", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			u, _ := url.Parse(config.URL.value)
+			header := buildHeader(config.Header)
+			var message *wsprobe.Message
+			if config.Send != "" || config.Ping {
+				message = &wsprobe.Message{Ping: config.Ping, Text: config.Send, Expect: config.Expect}
+			}
+			tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+			return newResult(p.webSocketProber.Probe(u, header, config.Subprotocol, message, tlsConfig, timeLeft))
+		}})
+	}
+	for _, config := range p.config.Service.File {
+		config := config
+		checks = append(checks, check{config.Name, "file", 0, config.checkOptions, func(time.Duration) checkResult {
+			return newResult(p.fileProber.Probe(config.Path, config.Readable, config.Writable, time.Duration(config.MaxAge)))
+		}})
+	}
+	for _, config := range p.config.Service.Disk {
+		config := config
+		checks = append(checks, check{config.Name, "disk", 0, config.checkOptions, func(time.Duration) checkResult {
+			return newResult(p.diskProber.Probe(config.Path, diskprobe.Threshold{
+				FreePercent:       config.MinFreePercent,
+				FreeBytes:         config.MinFreeBytes,
+				FreeInodesPercent: config.MinFreeInodesPercent,
+				FreeInodes:        config.MinFreeInodes,
+			}))
+		}})
+	}
+	for _, config := range p.config.Service.Process {
+		config := config
+		checks = append(checks, check{config.Name, "process", 0, config.checkOptions, func(time.Duration) checkResult {
+			selector := processprobe.Selector{Name: config.Command, PIDFile: config.PIDFile}
+			if config.Cmdline != "" {
+				selector.Cmdline = regexp.MustCompile(config.Cmdline)
+			}
+			limits := processprobe.Limits{MaxRSS: config.MaxRSS, MaxOpenFiles: config.MaxOpenFiles}
+			return newResult(p.processProber.Probe(selector, limits))
+		}})
+	}
+	for _, config := range p.config.Service.Nagios {
+		config := config
+		checks = append(checks, check{config.Name, "nagios", time.Duration(config.TimeOut), config.checkOptions, func(timeLeft time.Duration) checkResult {
+			plugin, err := p.nagiosProber.Probe(config.Cmd, timeLeft)
+			result := newResult(nagiosResult(plugin.Status), plugin.Text, err)
+			result.PluginStatus = &plugin.Status
+			result.Perfdata = plugin.Perfdata
+			return result
+		}})
+	}
+	return checks
+}
+
+// nagiosResult maps a plugin status to a probe result.
+func nagiosResult(status nagiosprobe.Status) probe.Result {
+	switch status {
+	case nagiosprobe.OK:
+		return probe.Success
+	case nagiosprobe.Warning:
+		return warning
+	case nagiosprobe.Critical:
+		return probe.Failure
+	}
+	return probe.Unknown
+}
 
-			errMsg := p.handleError(config.Name, health, output, err)
+func (p *prober) liveness(w http.ResponseWriter, r *http.Request) {
+	results := p.evaluateRequest(r)
+	status, errMsgs := p.summarize(results)
+	if p.config.StartupTimeout > 0 && p.startup != nil {
+		// Failures do not count until the prober has started.
+		if startup, _ := p.startup.status(); startup == statusStarting {
+			status = statusStarting
+		}
+	}
+	p.respond(w, r, status, errMsgs, results)
+}
 
-			if errMsg != "" {
-				ok = false
-				errMsgs = append(errMsgs, errMsg)
-			}
-		}(config)
+// respond writes the status of the results as liveness does: 200 unless
+// the status is FAIL, with the messages of the checks that did not succeed.
+func (p *prober) respond(w http.ResponseWriter, r *http.Request, status string, errMsgs []string, results []checkResult) {
+	for i, errMsg := range errMsgs {
+		errMsgs[i] = p.redact(errMsg)
 	}
-	wg.Wait()
-	if ok {
+	if status != statusOK && status != statusStarting {
+		glog.Warning(errMsgs)
+	}
+	code := http.StatusOK
+	if status == statusFail {
+		code = http.StatusServiceUnavailable
+	}
+	if wantsJSON(r) {
+		writeJSON(w, code, livenessResponse{Status: status, Checks: results})
+		return
+	}
+	switch status {
+	case statusOK:
 		w.Write([]byte("OK"))
-	} else {
+	case statusDegraded, statusStarting:
+		w.Write([]byte(status + "\n" + strings.Join(errMsgs, "")))
+	default:
 		// Send 503
-		glog.Warning(errMsgs)
 		http.Error(w, strings.Join(errMsgs, ""), http.StatusServiceUnavailable)
 	}
 }
 
+// summarize returns the overall status of the results and the messages of
+// the checks that did not succeed. Failing critical checks fail the prober,
+// warnings and failing non-critical checks only degrade it.
+func (p *prober) summarize(results []checkResult) (string, []string) {
+	status := statusOK
+	var errMsgs []string
+	for _, result := range results {
+		if result.Result == pending {
+			continue
+		}
+		errMsg := p.handleError(result.Name, result.Result, result.Output, result.err)
+		if errMsg == "" {
+			continue
+		}
+		errMsgs = append(errMsgs, errMsg)
+		if result.Result != warning && result.Severity != severityNonCritical {
+			status = statusFail
+		} else if status == statusOK {
+			status = statusDegraded
+		}
+	}
+	return status, errMsgs
+}
+
 func (p *prober) handleError(configName string, health probe.Result, output string, err error) string {
 	errMsg := ""
 	if health != probe.Success {
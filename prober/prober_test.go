@@ -1,17 +1,31 @@
 package prober
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/glog"
 	"k8s.io/kubernetes/pkg/probe"
+
+	nagiosprobe "github.com/tony24681379/service-prober/probe/nagios"
 )
 
 func TestGetConfigType(t *testing.T) {
@@ -23,7 +37,9 @@ func TestGetConfigType(t *testing.T) {
 		{"abc.yaml", "yaml", nil},
 		{"123.yml", "yaml", nil},
 		{"abc123.json", "json", nil},
-		{"abc.asd.a", "", errors.New("please use yaml or json config file")},
+		{"abc.toml", "toml", nil},
+		{"abc.hcl", "hcl", nil},
+		{"abc.asd.a", "", errors.New("please use yaml, json, toml or hcl config file")},
 	}
 	for i, tt := range tests {
 		c := probeConfig{}
@@ -42,32 +58,52 @@ func TestGetConfigType(t *testing.T) {
 func TestConvertDataToStruct(t *testing.T) {
 	expectedServics :=
 		service{
-			[]tcpService{
+			TCP: []tcpService{
 				{
 					Name:    "casandra",
 					IP:      "127.0.0.1",
 					Port:    9042,
-					TimeOut: time.Duration(15) * time.Second,
+					TimeOut: duration(15 * time.Second),
 				},
 			},
-			[]httpService{
+			HTTP: []httpService{
 				{
 					Name: "mongo",
-					URL:  "http://127.0.0.1:27017",
+					URL:  configString{value: "http://127.0.0.1:27017"},
 					Header: []httpHeader{
 						{
 							Name:  "X-Muffins-Or-Cupcakes",
-							Value: "Muffins",
+							Value: configString{value: "Muffins"},
 						},
 						{
 							Name:  "X-Muffins-Or-Plumcakes",
-							Value: "Muffins!",
+							Value: configString{value: "Muffins!"},
 						},
 					},
-					TimeOut: time.Duration(15) * time.Second,
+					TimeOut: duration(15 * time.Second),
+				},
+			},
+			Etcd: []etcdService{
+				{
+					Name:            "etcd",
+					Endpoints:       []configString{{value: "http://127.0.0.1:2379"}, {value: "http://127.0.0.2:2379"}},
+					MaxRaftIndexLag: 100,
+					TimeOut:         duration(5 * time.Second),
+					checkOptions:    checkOptions{Severity: "non-critical"},
+				},
+			},
+			ZooKeeper: []zookeeperService{
+				{
+					Name:                   "zookeeper",
+					Servers:                []string{"127.0.0.1:2181"},
+					MaxOutstandingRequests: 10,
+					TimeOut:                duration(5 * time.Second),
 				},
 			},
 		}
+	expectedGroups := []checkGroup{
+		{Name: "coordination", Checks: []string{"etcd", "zookeeper"}, AtLeast: 1},
+	}
 	tests := []struct {
 		expectedConfigType string
 		configFile         []byte
@@ -78,6 +114,10 @@ func TestConvertDataToStruct(t *testing.T) {
 			"yaml",
 			[]byte(`
 ---
+concurrency: 10
+deadline: 900ms
+initialDelay: 10s
+startupTimeout: 5m
 service:
   http:
   - name: mongo
@@ -93,10 +133,35 @@ service:
     ip: 127.0.0.1
     port: 9042
     timeout: 15s
+  etcd:
+  - name: etcd
+    endpoints:
+    - http://127.0.0.1:2379
+    - http://127.0.0.2:2379
+    maxRaftIndexLag: 100
+    timeout: 5s
+    severity: non-critical
+  zookeeper:
+  - name: zookeeper
+    servers:
+    - 127.0.0.1:2181
+    maxOutstandingRequests: 10
+    timeout: 5s
+groups:
+- name: coordination
+  checks:
+  - etcd
+  - zookeeper
+  atLeast: 1
 `),
 			probeConfig{
-				"yaml",
-				expectedServics,
+				configType:     "yaml",
+				Concurrency:    10,
+				Deadline:       duration(900 * time.Millisecond),
+				InitialDelay:   duration(10 * time.Second),
+				StartupTimeout: duration(5 * time.Minute),
+				Service:        expectedServics,
+				Groups:         expectedGroups,
 			},
 			nil,
 		},
@@ -104,12 +169,16 @@ service:
 			"json",
 			[]byte(`
 {
+    "concurrency": 10,
+    "deadline": "900ms",
+    "initialDelay": 10,
+    "startupTimeout": "5m",
     "service": {
         "tcp": [{
             "name": "casandra",
             "ip": "127.0.0.1",
             "port": 9042,
-            "timeout": 15000000000
+            "timeout": "15s"
         }],
         "http": [{
             "name": "mongo",
@@ -123,14 +192,156 @@ service:
                     "value": "Muffins!"
                 }
             ],
-            "timeout": 15000000000
+            "timeout": "15s"
+        }],
+        "etcd": [{
+            "name": "etcd",
+            "endpoints": ["http://127.0.0.1:2379", "http://127.0.0.2:2379"],
+            "maxRaftIndexLag": 100,
+            "timeout": "5s",
+            "severity": "non-critical"
+        }],
+        "zookeeper": [{
+            "name": "zookeeper",
+            "servers": ["127.0.0.1:2181"],
+            "maxOutstandingRequests": 10,
+            "timeout": "5s"
         }]
+    },
+    "groups": [{
+        "name": "coordination",
+        "checks": ["etcd", "zookeeper"],
+        "atLeast": 1
+    }]
+}
+`),
+			probeConfig{
+				configType:     "json",
+				Concurrency:    10,
+				Deadline:       duration(900 * time.Millisecond),
+				InitialDelay:   duration(10 * time.Second),
+				StartupTimeout: duration(5 * time.Minute),
+				Service:        expectedServics,
+				Groups:         expectedGroups,
+			},
+			nil,
+		},
+		{
+			"toml",
+			[]byte(`
+concurrency = 10
+deadline = "900ms"
+initialDelay = 10
+startupTimeout = "5m"
+
+[[service.tcp]]
+name = "casandra"
+ip = "127.0.0.1"
+port = 9042
+timeout = "15s"
+
+[[service.http]]
+name = "mongo"
+url = "http://127.0.0.1:27017"
+timeout = "15s"
+
+[[service.http.header]]
+name = "X-Muffins-Or-Cupcakes"
+value = "Muffins"
+
+[[service.http.header]]
+name = "X-Muffins-Or-Plumcakes"
+value = "Muffins!"
+
+[[service.etcd]]
+name = "etcd"
+endpoints = ["http://127.0.0.1:2379", "http://127.0.0.2:2379"]
+maxRaftIndexLag = 100
+timeout = "5s"
+severity = "non-critical"
+
+[[service.zookeeper]]
+name = "zookeeper"
+servers = ["127.0.0.1:2181"]
+maxOutstandingRequests = 10
+timeout = "5s"
+
+[[groups]]
+name = "coordination"
+checks = ["etcd", "zookeeper"]
+atLeast = 1
+`),
+			probeConfig{
+				configType:     "toml",
+				Concurrency:    10,
+				Deadline:       duration(900 * time.Millisecond),
+				InitialDelay:   duration(10 * time.Second),
+				StartupTimeout: duration(5 * time.Minute),
+				Service:        expectedServics,
+				Groups:         expectedGroups,
+			},
+			nil,
+		},
+		{
+			"hcl",
+			[]byte(`
+concurrency = 10
+deadline = "900ms"
+initialDelay = 10
+startupTimeout = "5m"
+
+service {
+  tcp {
+    name = "casandra"
+    ip = "127.0.0.1"
+    port = 9042
+    timeout = "15s"
+  }
+
+  http {
+    name = "mongo"
+    url = "http://127.0.0.1:27017"
+    header {
+      name = "X-Muffins-Or-Cupcakes"
+      value = "Muffins"
+    }
+    header {
+      name = "X-Muffins-Or-Plumcakes"
+      value = "Muffins!"
     }
+    timeout = "15s"
+  }
+
+  etcd {
+    name = "etcd"
+    endpoints = ["http://127.0.0.1:2379", "http://127.0.0.2:2379"]
+    maxRaftIndexLag = 100
+    timeout = "5s"
+    severity = "non-critical"
+  }
+
+  zookeeper {
+    name = "zookeeper"
+    servers = ["127.0.0.1:2181"]
+    maxOutstandingRequests = 10
+    timeout = "5s"
+  }
+}
+
+groups {
+  name = "coordination"
+  checks = ["etcd", "zookeeper"]
+  atLeast = 1
 }
 `),
 			probeConfig{
-				"json",
-				expectedServics,
+				configType:     "hcl",
+				Concurrency:    10,
+				Deadline:       duration(900 * time.Millisecond),
+				InitialDelay:   duration(10 * time.Second),
+				StartupTimeout: duration(5 * time.Minute),
+				Service:        expectedServics,
+				Groups:         expectedGroups,
 			},
 			nil,
 		},
@@ -168,6 +379,24 @@ func (p fakeTCPProber) Probe(host string, port int, timeout time.Duration) (prob
 	return p.result, "message", p.err
 }
 
+type fakeEtcdProber struct {
+	result probe.Result
+	err    error
+}
+
+func (p fakeEtcdProber) Probe(endpoints []*url.URL, tlsConfig *tls.Config, maxRaftIndexLag uint64, timeout time.Duration) (probe.Result, string, error) {
+	return p.result, "message", p.err
+}
+
+type fakeZooKeeperProber struct {
+	result probe.Result
+	err    error
+}
+
+func (p fakeZooKeeperProber) Probe(servers []string, maxOutstandingRequests int, timeout time.Duration) (probe.Result, string, error) {
+	return p.result, "message", p.err
+}
+
 func TestLiveness(t *testing.T) {
 	tests := []struct {
 		probe          *prober
@@ -179,8 +408,8 @@ func TestLiveness(t *testing.T) {
 				httpProber: fakeHTTPProber{result: probe.Success},
 				config: probeConfig{
 					Service: service{
-						[]tcpService{{Name: "casandra"}},
-						[]httpService{{Name: "mongo"}},
+						TCP:  []tcpService{{Name: "casandra"}},
+						HTTP: []httpService{{Name: "mongo"}},
 					},
 				},
 			},
@@ -192,8 +421,8 @@ func TestLiveness(t *testing.T) {
 				httpProber: fakeHTTPProber{result: probe.Failure},
 				config: probeConfig{
 					Service: service{
-						[]tcpService{{Name: "casandra"}},
-						[]httpService{{Name: "mongo"}},
+						TCP:  []tcpService{{Name: "casandra"}},
+						HTTP: []httpService{{Name: "mongo"}},
 					},
 				},
 			},
@@ -205,12 +434,40 @@ func TestLiveness(t *testing.T) {
 				httpProber: fakeHTTPProber{result: probe.Failure},
 				config: probeConfig{
 					Service: service{
-						[]tcpService{{Name: "casandra"}},
-						[]httpService{{Name: "mongo"}},
+						TCP:  []tcpService{{Name: "casandra"}},
+						HTTP: []httpService{{Name: "mongo"}},
 					},
 				},
 			},
-			[]byte("mongo message\ncasandra message\n\n"),
+			[]byte("casandra message\nmongo message\n\n"),
+		},
+		{
+			&prober{
+				tcpProber:       fakeTCPProber{result: probe.Success},
+				etcdProber:      fakeEtcdProber{result: probe.Success},
+				zookeeperProber: fakeZooKeeperProber{result: probe.Failure},
+				config: probeConfig{
+					Service: service{
+						TCP:       []tcpService{{Name: "casandra"}},
+						Etcd:      []etcdService{{Name: "etcd"}},
+						ZooKeeper: []zookeeperService{{Name: "zookeeper"}},
+					},
+				},
+			},
+			[]byte("zookeeper message\n\n"),
+		},
+		{
+			&prober{
+				tcpProber:  fakeTCPProber{result: probe.Success},
+				httpProber: fakeHTTPProber{result: probe.Failure},
+				config: probeConfig{
+					Service: service{
+						TCP:  []tcpService{{Name: "casandra"}},
+						HTTP: []httpService{{Name: "mongo", checkOptions: checkOptions{Severity: "non-critical"}}},
+					},
+				},
+			},
+			[]byte("DEGRADED\nmongo message\n"),
 		},
 	}
 
@@ -232,6 +489,120 @@ func TestLiveness(t *testing.T) {
 	}
 }
 
+type fakeNagiosProber struct {
+	result nagiosprobe.Result
+	err    error
+}
+
+func (p fakeNagiosProber) Probe(cmd []string, timeout time.Duration) (nagiosprobe.Result, error) {
+	return p.result, p.err
+}
+
+func TestLivenessJSON(t *testing.T) {
+	warning := nagiosprobe.Warning
+	critical := nagiosprobe.Critical
+	unknown := nagiosprobe.Unknown
+	perfdata := []nagiosprobe.Perfdata{{Label: "load1", Value: 4.2, Warn: "4", Crit: "8"}}
+	tests := []struct {
+		probe          *prober
+		expectedCode   int
+		expectedResult livenessResponse
+	}{
+		{
+			&prober{
+				nagiosProber: fakeNagiosProber{result: nagiosprobe.Result{Status: warning, Text: "LOAD WARNING", Perfdata: perfdata}},
+				config: probeConfig{
+					Service: service{
+						Nagios: []nagiosService{{Name: "load"}},
+					},
+				},
+			},
+			http.StatusOK,
+			livenessResponse{
+				Status: "DEGRADED",
+				Checks: []checkResult{
+					{Name: "load", Type: "nagios", Severity: "critical", Result: "warning", Output: "LOAD WARNING", PluginStatus: &warning, Perfdata: perfdata},
+				},
+			},
+		},
+		{
+			&prober{
+				nagiosProber: fakeNagiosProber{result: nagiosprobe.Result{Status: critical, Text: "LOAD CRITICAL"}},
+				config: probeConfig{
+					Service: service{
+						Nagios: []nagiosService{{Name: "load"}},
+					},
+				},
+			},
+			http.StatusServiceUnavailable,
+			livenessResponse{
+				Status: "FAIL",
+				Checks: []checkResult{
+					{Name: "load", Type: "nagios", Severity: "critical", Result: probe.Failure, Output: "LOAD CRITICAL", PluginStatus: &critical},
+				},
+			},
+		},
+		{
+			&prober{
+				nagiosProber: fakeNagiosProber{result: nagiosprobe.Result{Status: critical, Text: "LOAD CRITICAL"}},
+				config: probeConfig{
+					Service: service{
+						Nagios: []nagiosService{{Name: "load", checkOptions: checkOptions{Severity: "non-critical"}}},
+					},
+				},
+			},
+			http.StatusOK,
+			livenessResponse{
+				Status: "DEGRADED",
+				Checks: []checkResult{
+					{Name: "load", Type: "nagios", Severity: "non-critical", Result: probe.Failure, Output: "LOAD CRITICAL", PluginStatus: &critical},
+				},
+			},
+		},
+		{
+			&prober{
+				nagiosProber: fakeNagiosProber{result: nagiosprobe.Result{Status: unknown, Text: "plugin timed out"}},
+				config: probeConfig{
+					Service: service{
+						Nagios: []nagiosService{{Name: "load"}},
+					},
+				},
+			},
+			http.StatusServiceUnavailable,
+			livenessResponse{
+				Status: "FAIL",
+				Checks: []checkResult{
+					{Name: "load", Type: "nagios", Severity: "critical", Result: probe.Unknown, Output: "plugin timed out", PluginStatus: &unknown},
+				},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(tt.probe.liveness))
+		defer ts.Close()
+		res, err := http.Get(ts.URL + "?format=json")
+		if err != nil {
+			glog.Fatal(err)
+		}
+		var response livenessResponse
+		err = json.NewDecoder(res.Body).Decode(&response)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if res.StatusCode != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, res.StatusCode)
+		}
+		for j := range response.Checks {
+			response.Checks[j].Duration = 0
+		}
+		if !reflect.DeepEqual(response, tt.expectedResult) {
+			t.Errorf("#%d: expected result=%+v, get=%+v", i, tt.expectedResult, response)
+		}
+	}
+}
+
 func TestHTTPHeaders(t *testing.T) {
 	testCases := []struct {
 		input  []httpHeader
@@ -239,16 +610,16 @@ func TestHTTPHeaders(t *testing.T) {
 	}{
 		{[]httpHeader{}, http.Header{}},
 		{[]httpHeader{
-			{Name: "X-Muffins-Or-Cupcakes", Value: "Muffins"},
+			{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Muffins"}},
 		}, http.Header{"X-Muffins-Or-Cupcakes": {"Muffins"}}},
 		{[]httpHeader{
-			{Name: "X-Muffins-Or-Cupcakes", Value: "Muffins"},
-			{Name: "X-Muffins-Or-Plumcakes", Value: "Muffins!"},
+			{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Muffins"}},
+			{Name: "X-Muffins-Or-Plumcakes", Value: configString{value: "Muffins!"}},
 		}, http.Header{"X-Muffins-Or-Cupcakes": {"Muffins"},
 			"X-Muffins-Or-Plumcakes": {"Muffins!"}}},
 		{[]httpHeader{
-			{Name: "X-Muffins-Or-Cupcakes", Value: "Muffins"},
-			{Name: "X-Muffins-Or-Cupcakes", Value: "Cupcakes, too"},
+			{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Muffins"}},
+			{Name: "X-Muffins-Or-Cupcakes", Value: configString{value: "Cupcakes, too"}},
 		}, http.Header{"X-Muffins-Or-Cupcakes": {"Muffins", "Cupcakes, too"}}},
 	}
 	for _, test := range testCases {
@@ -258,3 +629,70 @@ func TestHTTPHeaders(t *testing.T) {
 		}
 	}
 }
+
+// writeCertificate writes a self-signed certificate and its key as PEM
+// files in dir.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "etcd"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestEtcdTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := etcdService{Name: "etcd", CA: configString{value: certFile}, Cert: configString{value: certFile}, Key: configString{value: keyFile}}.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RootCAs == nil || len(config.Certificates) != 1 || config.InsecureSkipVerify {
+		t.Errorf("expected the ca and client certificate, get=%+v", config)
+	}
+
+	tests := []struct {
+		service       etcdService
+		expectedError string
+	}{
+		{etcdService{Name: "etcd", Cert: configString{value: certFile}}, "etcd: etcd cert and key must be set together"},
+		{etcdService{Name: "etcd", CA: configString{value: keyFile}}, "etcd: no certificate in etcd ca " + keyFile},
+		{etcdService{Name: "etcd", CA: configString{value: filepath.Join(dir, "missing.pem")}}, "no such file or directory"},
+		{etcdService{Name: "etcd", Cert: configString{value: keyFile}, Key: configString{value: keyFile}}, "failed to find certificate PEM data in certificate input"},
+	}
+	for i, tt := range tests {
+		_, err := tt.service.tlsConfig()
+		if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+
+	c := probeConfig{Service: service{Etcd: []etcdService{{Name: "etcd", Endpoints: []configString{{value: "https://127.0.0.1:2379"}}, Key: configString{value: keyFile}}}}}
+	if err := c.validate(); err == nil {
+		t.Errorf("expected validate to check the etcd tls files")
+	}
+}
@@ -0,0 +1,249 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/kubernetes/pkg/probe"
+
+	nagiosprobe "github.com/tony24681379/service-prober/probe/nagios"
+)
+
+// warning is the result of a check that works but reports a problem.
+const warning probe.Result = "warning"
+
+// timeout is the result of a check that did not finish before the request
+// was canceled or the deadline passed.
+const timeout probe.Result = "timeout"
+
+// Overall status of the prober.
+const (
+	statusOK       = "OK"
+	statusDegraded = "DEGRADED"
+	statusFail     = "FAIL"
+)
+
+const (
+	severityCritical    = "critical"
+	severityNonCritical = "non-critical"
+)
+
+// checkResult is the outcome of a single evaluation of a check.
+type checkResult struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	// Source is the file of the config directory the check is defined in.
+	Source   string       `json:"source,omitempty"`
+	Result   probe.Result `json:"result"`
+	Output   string       `json:"output,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Duration float64      `json:"durationSeconds"`
+	// PluginStatus and Perfdata are reported by nagios checks.
+	PluginStatus *nagiosprobe.Status    `json:"pluginStatus,omitempty"`
+	Perfdata     []nagiosprobe.Perfdata `json:"perfdata,omitempty"`
+	// Attempts and AttemptErrors are reported by checks with retries.
+	Attempts      int      `json:"attempts,omitempty"`
+	AttemptErrors []string `json:"attemptErrors,omitempty"`
+	// Breaker is the state of the circuit breaker of the check.
+	Breaker string `json:"breaker,omitempty"`
+	// Flapping and StateChange are reported by checks with flap detection.
+	Flapping    bool     `json:"flapping,omitempty"`
+	StateChange *float64 `json:"stateChangePercent,omitempty"`
+	// Members are the results of the checks of a group.
+	Members []checkResult `json:"members,omitempty"`
+	err     error
+}
+
+// livenessResponse is the JSON body of the liveness endpoint.
+type livenessResponse struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+func newResult(health probe.Result, output string, err error) checkResult {
+	result := checkResult{Result: health, Output: output, err: err}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// evaluate runs every check and returns their results in config order.
+func (p *prober) evaluate(ctx context.Context) []checkResult {
+	return p.evaluateChecks(ctx, p.checks())
+}
+
+// evaluateChecks runs the checks concurrently and returns their results in
+// the order of checks, which must include their dependencies. A check waits
+// for its dependencies and is skipped when one of them fails. Checks that
+// have not finished when ctx is done or the deadline passes are reported as
+// timeout.
+func (p *prober) evaluateChecks(ctx context.Context, checks []check) []checkResult {
+	if p.config.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.config.Deadline))
+		defer cancel()
+	}
+	start := time.Now()
+	// Every check writes only its own result and closes its done channel,
+	// after which the result may be read.
+	results := make([]checkResult, len(checks))
+	done := make([]chan struct{}, len(checks))
+	index := make(map[string]int, len(checks))
+	for i, c := range checks {
+		done[i] = make(chan struct{})
+		index[c.name] = i
+	}
+
+	for i, c := range checks {
+		go func(i int, c check) {
+			defer close(done[i])
+			results[i] = p.run(ctx, c, results, done, index, start)
+		}(i, c)
+	}
+
+	// Abandoned checks keep running in the background until the deadline,
+	// which bounds the time their probes get, their results are never read.
+	collected := make([]checkResult, len(checks))
+	for i, c := range checks {
+		select {
+		case <-done[i]:
+			collected[i] = results[i]
+			continue
+		default:
+		}
+		select {
+		case <-done[i]:
+			collected[i] = results[i]
+		case <-ctx.Done():
+			collected[i] = timedOut(ctx, c, start)
+		}
+	}
+	for i := range collected {
+		collected[i].Source = p.config.sources[collected[i].Name]
+		collected[i] = p.detectFlaps(collected[i])
+	}
+	if p.startup != nil {
+		p.startup.record(collected)
+	}
+	applied := p.applyGroups(collected)
+	p.remember(applied)
+	return applied
+}
+
+// run waits for the dependencies of the check and a free slot, then probes
+// it. It gives up when ctx is done.
+func (p *prober) run(ctx context.Context, c check, results []checkResult, done []chan struct{}, index map[string]int, start time.Time) checkResult {
+	var result checkResult
+	if delay := p.initialDelay(c) - time.Since(p.started); delay > 0 {
+		result.Result = pending
+		result.Output = fmt.Sprintf("initial delay, probing in %v", delay.Round(time.Second))
+	}
+	for _, dependency := range c.options.DependsOn {
+		if result.Result != "" {
+			break
+		}
+		j := index[dependency]
+		select {
+		case <-done[j]:
+		case <-ctx.Done():
+			return timedOut(ctx, c, start)
+		}
+		switch results[j].Result {
+		case probe.Success, warning:
+		case pending:
+			result = checkResult{Result: pending, Output: "pending, dependency " + results[j].Name + " is pending"}
+		default:
+			result = skip(results[j])
+		}
+	}
+	if result.Result == "" {
+		if p.slots != nil {
+			select {
+			case p.slots <- struct{}{}:
+				defer func() { <-p.slots }()
+			case <-ctx.Done():
+				return timedOut(ctx, c, start)
+			}
+		}
+		// A probe that ends once ctx is done was cut short by it.
+		if !expired(ctx) {
+			result = p.probe(ctx, c)
+		}
+		if expired(ctx) {
+			<-ctx.Done()
+			return timedOut(ctx, c, start)
+		}
+	}
+	result.Name = c.name
+	result.Type = c.kind
+	result.Severity = c.severity()
+	return result
+}
+
+// probe probes the check through its circuit breaker, if any, giving up
+// when ctx is done.
+func (p *prober) probe(ctx context.Context, c check) checkResult {
+	b := p.breakers[c.name]
+	if b != nil {
+		if ok, failed := b.allow(); !ok {
+			return failed
+		}
+	}
+	start := time.Now()
+	result := p.redactResult(retry(ctx, c))
+	result.Duration = time.Since(start).Seconds()
+	if b != nil {
+		result = b.record(result)
+	}
+	return result
+}
+
+// expired reports whether ctx is done or its deadline has passed, which
+// ctx may report a little later.
+func expired(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+// timedOut is the result of a check given up when ctx is done.
+func timedOut(ctx context.Context, c check, start time.Time) checkResult {
+	return checkResult{
+		Name:     c.name,
+		Type:     c.kind,
+		Severity: c.severity(),
+		Result:   timeout,
+		Output:   "check did not finish: " + ctx.Err().Error(),
+		Duration: time.Since(start).Seconds(),
+	}
+}
+
+// wantsJSON reports whether the client asked for a JSON response, with
+// ?format=json or an Accept header.
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		glog.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
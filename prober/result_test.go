@@ -0,0 +1,177 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// fakeSlowTCPProber answers with the host after a random delay, so checks
+// finish in a different order than they are configured.
+type fakeSlowTCPProber struct{}
+
+func (p fakeSlowTCPProber) Probe(host string, port int, timeout time.Duration) (probe.Result, string, error) {
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+	return probe.Success, host, nil
+}
+
+func TestEvaluateOrder(t *testing.T) {
+	var tcp []tcpService
+	for i := 0; i < 50; i++ {
+		service := tcpService{Name: fmt.Sprintf("tcp-%d", i), IP: fmt.Sprintf("host-%d", i)}
+		if i%5 == 4 {
+			service.DependsOn = []string{fmt.Sprintf("tcp-%d", i-1)}
+		}
+		tcp = append(tcp, service)
+	}
+	p := &prober{tcpProber: fakeSlowTCPProber{}, config: probeConfig{Service: service{TCP: tcp}}}
+	for run := 0; run < 10; run++ {
+		results := p.evaluate(context.Background())
+		if len(results) != len(tcp) {
+			t.Fatalf("expected %d results, get %d", len(tcp), len(results))
+		}
+		for i, result := range results {
+			if result.Name != tcp[i].Name || result.Output != tcp[i].IP {
+				t.Errorf("#%d: expected name=%s output=%s, get name=%s output=%s", i, tcp[i].Name, tcp[i].IP, result.Name, result.Output)
+			}
+		}
+	}
+}
+
+// fakeBlockingTCPProber succeeds after the delay of the host, and counts
+// the probes running at once.
+type fakeBlockingTCPProber struct {
+	delay   map[string]time.Duration
+	running *int32
+	max     *int32
+}
+
+func (p fakeBlockingTCPProber) Probe(host string, port int, timeout time.Duration) (probe.Result, string, error) {
+	n := atomic.AddInt32(p.running, 1)
+	defer atomic.AddInt32(p.running, -1)
+	for {
+		max := atomic.LoadInt32(p.max)
+		if n <= max || atomic.CompareAndSwapInt32(p.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(p.delay[host])
+	return probe.Success, "", nil
+}
+
+func TestEvaluateDeadline(t *testing.T) {
+	p := newProber(&probeConfig{
+		Deadline: duration(50 * time.Millisecond),
+		Service: service{
+			TCP: []tcpService{
+				{Name: "fast", IP: "fast"},
+				{Name: "slow", IP: "slow"},
+				{Name: "after-slow", IP: "fast", checkOptions: checkOptions{DependsOn: []string{"slow"}}},
+			},
+		},
+	})
+	p.tcpProber = fakeBlockingTCPProber{
+		delay:   map[string]time.Duration{"slow": time.Second},
+		running: new(int32),
+		max:     new(int32),
+	}
+	start := time.Now()
+	results := p.evaluate(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected evaluate to return at the deadline, took %v", elapsed)
+	}
+	expected := []probe.Result{probe.Success, timeout, timeout}
+	for i, result := range results {
+		if result.Result != expected[i] {
+			t.Errorf("#%d: expected result=%v, get=%v", i, expected[i], result.Result)
+		}
+	}
+}
+
+// fakeHangingTCPProber fails at the timeout of the probe for every host
+// but instant, like a host that drops the packets.
+type fakeHangingTCPProber struct{}
+
+func (p fakeHangingTCPProber) Probe(host string, port int, timeout time.Duration) (probe.Result, string, error) {
+	if host == "instant" {
+		return probe.Success, "", nil
+	}
+	time.Sleep(timeout)
+	return probe.Failure, "i/o timeout", nil
+}
+
+// Checks abandoned at the deadline must give back their slot, so that they
+// do not starve the other checks on the next evaluations.
+func TestEvaluateAbandonedReleasesSlot(t *testing.T) {
+	p := newProber(&probeConfig{
+		Concurrency: 1,
+		Deadline:    duration(100 * time.Millisecond),
+		Service: service{
+			TCP: []tcpService{
+				{Name: "slow", IP: "slow", TimeOut: duration(15 * time.Second)},
+				{Name: "instant", IP: "instant", TimeOut: duration(15 * time.Second)},
+			},
+		},
+	})
+	p.tcpProber = fakeHangingTCPProber{}
+	checks := p.checks()
+	tests := []struct {
+		check          check
+		expectedResult probe.Result
+	}{
+		{checks[0], timeout},
+		{checks[1], probe.Success},
+		{checks[0], timeout},
+		{checks[1], probe.Success},
+	}
+	for i, tt := range tests {
+		results := p.evaluateChecks(context.Background(), []check{tt.check})
+		if results[0].Result != tt.expectedResult {
+			t.Errorf("#%d: expected result=%v, get=%v (%s)", i, tt.expectedResult, results[0].Result, results[0].Output)
+		}
+	}
+}
+
+func TestEvaluateCanceled(t *testing.T) {
+	p := &prober{
+		tcpProber: fakeBlockingTCPProber{
+			delay:   map[string]time.Duration{"slow": time.Second},
+			running: new(int32),
+			max:     new(int32),
+		},
+		config: probeConfig{Service: service{TCP: []tcpService{{Name: "slow", IP: "slow"}}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := p.evaluate(ctx)
+	if results[0].Result != timeout || results[0].Output != "check did not finish: context canceled" {
+		t.Errorf("expected result=%v, get=%v output=%q", timeout, results[0].Result, results[0].Output)
+	}
+}
+
+func TestEvaluateConcurrency(t *testing.T) {
+	var tcp []tcpService
+	for i := 0; i < 20; i++ {
+		tcp = append(tcp, tcpService{Name: fmt.Sprintf("tcp-%d", i), IP: "host"})
+	}
+	p := newProber(&probeConfig{Concurrency: 3, Service: service{TCP: tcp}})
+	fake := fakeBlockingTCPProber{
+		delay:   map[string]time.Duration{"host": 5 * time.Millisecond},
+		running: new(int32),
+		max:     new(int32),
+	}
+	p.tcpProber = fake
+	for _, result := range p.evaluate(context.Background()) {
+		if result.Result != probe.Success {
+			t.Errorf("%s: expected result=%v, get=%v", result.Name, probe.Success, result.Result)
+		}
+	}
+	if max := atomic.LoadInt32(fake.max); max != 3 {
+		t.Errorf("expected at most 3 checks at once, get=%d", max)
+	}
+}
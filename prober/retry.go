@@ -0,0 +1,134 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// Backoff policies.
+const (
+	backoffConstant    = "constant"
+	backoffExponential = "exponential"
+)
+
+const defaultBackoffInterval = 100 * time.Millisecond
+
+// backoffOptions sets the wait between the attempts of a check.
+type backoffOptions struct {
+	// Policy is constant, the default, or exponential, which doubles the
+	// interval after every attempt up to MaxInterval.
+	Policy      string
+	Interval    duration
+	MaxInterval duration `json:"maxInterval" yaml:"maxInterval"`
+	// Jitter shortens every wait by a random fraction of at most Jitter.
+	Jitter float64
+}
+
+func (o backoffOptions) validate(name string) error {
+	switch o.Policy {
+	case "", backoffConstant, backoffExponential:
+	default:
+		return errors.New(name + ": backoff policy must be " + backoffConstant + " or " + backoffExponential)
+	}
+	if o.Interval < 0 || o.MaxInterval < 0 {
+		return errors.New(name + ": backoff intervals must not be negative")
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		return errors.New(name + ": backoff jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// wait returns how long to wait after the given attempt, counted from 1.
+func (o backoffOptions) wait(attempt int) time.Duration {
+	interval := time.Duration(o.Interval)
+	if interval == 0 {
+		interval = defaultBackoffInterval
+	}
+	if o.Policy == backoffExponential {
+		for i := 1; i < attempt; i++ {
+			interval *= 2
+			if o.MaxInterval > 0 && interval >= time.Duration(o.MaxInterval) {
+				interval = time.Duration(o.MaxInterval)
+				break
+			}
+		}
+	}
+	if o.Jitter > 0 {
+		interval -= time.Duration(rand.Float64() * o.Jitter * float64(interval))
+	}
+	return interval
+}
+
+// retry probes the check until it succeeds, it has been retried as many
+// times as configured, its timeout is spent or ctx is done. Every attempt
+// gets the time left of the timeout, bounded by the deadline of ctx so that
+// an abandoned check gives up at the deadline.
+func retry(ctx context.Context, c check) checkResult {
+	var backoff backoffOptions
+	if c.options.Backoff != nil {
+		backoff = *c.options.Backoff
+	}
+	timeLeft := c.timeout
+	deadline := time.Now().Add(c.timeout)
+	bounded := c.timeout > 0
+	if d, ok := ctx.Deadline(); ok && (!bounded || d.Before(deadline)) {
+		deadline, bounded = d, true
+		if timeLeft = time.Until(deadline); timeLeft <= 0 {
+			return newResult(timeout, "check did not finish: "+context.DeadlineExceeded.Error(), nil)
+		}
+	}
+	var errs []string
+	for attempt := 1; ; attempt++ {
+		result := c.probe(timeLeft)
+		if result.Result == probe.Success || result.Result == warning || attempt > c.options.Retries {
+			return attempted(result, attempt, errs)
+		}
+		errs = append(errs, attemptError(result))
+		wait := backoff.wait(attempt)
+		if bounded {
+			timeLeft = time.Until(deadline) - wait
+			if timeLeft <= 0 {
+				return attempted(result, attempt, errs[:len(errs)-1])
+			}
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return attempted(result, attempt, errs[:len(errs)-1])
+		}
+	}
+}
+
+// attempted records the attempts of a check with retries in its result.
+// errs are the errors of the attempts before the last one.
+func attempted(result checkResult, attempts int, errs []string) checkResult {
+	if attempts == 1 {
+		return result
+	}
+	result.Attempts = attempts
+	result.AttemptErrors = errs
+	note := fmt.Sprintf("attempt %d, previous: %s", attempts, strings.Join(errs, "; "))
+	if result.Output == "" {
+		result.Output = note
+	} else {
+		result.Output += " (" + note + ")"
+	}
+	return result
+}
+
+func attemptError(result checkResult) string {
+	switch {
+	case result.Error != "":
+		return result.Error
+	case result.Output != "":
+		return result.Output
+	}
+	return string(result.Result)
+}
@@ -0,0 +1,124 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func TestBackoffWait(t *testing.T) {
+	tests := []struct {
+		backoff  backoffOptions
+		attempt  int
+		expected time.Duration
+	}{
+		{backoffOptions{}, 3, 100 * time.Millisecond},
+		{backoffOptions{Interval: duration(time.Second)}, 3, time.Second},
+		{backoffOptions{Policy: backoffExponential, Interval: duration(time.Second)}, 1, time.Second},
+		{backoffOptions{Policy: backoffExponential, Interval: duration(time.Second)}, 4, 8 * time.Second},
+		{backoffOptions{Policy: backoffExponential, Interval: duration(time.Second), MaxInterval: duration(5 * time.Second)}, 4, 5 * time.Second},
+	}
+	for i, tt := range tests {
+		if wait := tt.backoff.wait(tt.attempt); wait != tt.expected {
+			t.Errorf("#%d: expected wait=%v, get=%v", i, tt.expected, wait)
+		}
+	}
+	jittered := backoffOptions{Interval: duration(time.Second), Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if wait := jittered.wait(1); wait < 500*time.Millisecond || wait > time.Second {
+			t.Fatalf("expected wait between 500ms and 1s, get=%v", wait)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	backoff := &backoffOptions{Interval: duration(time.Millisecond)}
+	tests := []struct {
+		timeout          time.Duration
+		retries          int
+		failures         int
+		expectedResult   probe.Result
+		expectedAttempts int
+		expectedOutput   string
+	}{
+		{0, 0, 1, probe.Failure, 0, "refused 1"},
+		{0, 2, 0, probe.Success, 0, ""},
+		{0, 2, 1, probe.Success, 2, "attempt 2, previous: refused 1"},
+		{0, 2, 5, probe.Failure, 3, "refused 3 (attempt 3, previous: refused 1; refused 2)"},
+		// The timeout is spent before the second attempt.
+		{time.Millisecond, 2, 5, probe.Failure, 0, "refused 1"},
+	}
+	for i, tt := range tests {
+		attempt := 0
+		c := check{
+			name:    "mongo",
+			timeout: tt.timeout,
+			options: checkOptions{Retries: tt.retries, Backoff: backoff},
+			probe: func(timeLeft time.Duration) checkResult {
+				attempt++
+				if attempt <= tt.failures {
+					return newResult(probe.Failure, fmt.Sprintf("refused %d", attempt), nil)
+				}
+				return newResult(probe.Success, "", nil)
+			},
+		}
+		result := retry(context.Background(), c)
+		if result.Result != tt.expectedResult || result.Attempts != tt.expectedAttempts {
+			t.Errorf("#%d: expected result=%v attempts=%d, get result=%v attempts=%d", i, tt.expectedResult, tt.expectedAttempts, result.Result, result.Attempts)
+		}
+		if result.Output != tt.expectedOutput {
+			t.Errorf("#%d: expected output=%q, get=%q", i, tt.expectedOutput, result.Output)
+		}
+	}
+}
+
+func TestRetryTimeLeft(t *testing.T) {
+	var timeLefts []time.Duration
+	c := check{
+		timeout: time.Second,
+		options: checkOptions{Retries: 1, Backoff: &backoffOptions{Interval: duration(100 * time.Millisecond)}},
+		probe: func(timeLeft time.Duration) checkResult {
+			timeLefts = append(timeLefts, timeLeft)
+			return newResult(probe.Failure, "connection refused", nil)
+		},
+	}
+	result := retry(context.Background(), c)
+	if !reflect.DeepEqual(result.AttemptErrors, []string{"connection refused"}) {
+		t.Errorf("expected errors=%v, get=%v", []string{"connection refused"}, result.AttemptErrors)
+	}
+	if len(timeLefts) != 2 || timeLefts[0] != time.Second || timeLefts[1] > 900*time.Millisecond {
+		t.Errorf("expected attempts within the time left of 1s, get=%v", timeLefts)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	var timeLefts []time.Duration
+	c := check{
+		timeout: 15 * time.Second,
+		options: checkOptions{Retries: 5, Backoff: &backoffOptions{Interval: duration(time.Second)}},
+		probe: func(timeLeft time.Duration) checkResult {
+			timeLefts = append(timeLefts, timeLeft)
+			return newResult(probe.Failure, "connection refused", nil)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result := retry(ctx, c)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected retry to give up when ctx is done, took %v", elapsed)
+	}
+	if result.Result != probe.Failure || len(timeLefts) != 1 || timeLefts[0] > 50*time.Millisecond {
+		t.Errorf("expected a single attempt within the deadline of ctx, get result=%v time lefts=%v", result.Result, timeLefts)
+	}
+
+	<-ctx.Done()
+	result = retry(ctx, c)
+	if result.Result != timeout || len(timeLefts) != 1 {
+		t.Errorf("expected no attempt once the deadline passed, get result=%v time lefts=%v", result.Result, timeLefts)
+	}
+}
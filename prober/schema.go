@@ -0,0 +1,288 @@
+package prober
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	sshprobe "github.com/tony24681379/service-prober/probe/ssh"
+)
+
+// jsonSchema is a JSON Schema, draft 7, of a config value.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*jsonSchema `json:"definitions,omitempty"`
+}
+
+// fieldSchema documents a config field. Fields are looked up by type and
+// name, as in "tcpService.port", then by name alone.
+type fieldSchema struct {
+	description string
+	enum        []interface{}
+	def         interface{}
+	min, max    *float64
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func enum(values ...string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+var fieldSchemas = map[string]fieldSchema{
+	"concurrency":    {description: "How many checks run at once, 0 means no limit.", def: 0, min: bound(0)},
+	"deadline":       {description: "Bounds a whole evaluation, checks that have not finished by then are reported as timeout. 0 means no deadline."},
+	"startupTimeout": {description: "Enables the startup mode, where liveness succeeds until every check has passed once or the timeout expires."},
+	"resultMaxAge":   {description: "How old the last state of a check reported by its endpoint may be before the check is probed again, 10s by default."},
+	"service":        {description: "The checks, by type."},
+	"groups":         {description: "Groups of checks evaluated as one."},
+	"defaults":       {description: "Fields every check of a type takes unless it sets them."},
+	"templates":      {description: "Named sets of fields checks take with extends."},
+
+	"tcp":       {description: "TCP checks, which connect to a port."},
+	"http":      {description: "HTTP checks, which expect a 2xx or 3xx response."},
+	"etcd":      {description: "etcd checks, which check the quorum of a cluster."},
+	"zookeeper": {description: "ZooKeeper checks, which check the quorum of an ensemble."},
+	"smtp":      {description: "SMTP checks, which expect the server greeting and EHLO."},
+	"imap":      {description: "IMAP checks, which expect the server greeting and capabilities."},
+	"ftp":       {description: "FTP checks, which expect the server greeting and log in when a user is set."},
+	"ssh":       {description: "SSH checks, which negotiate a key exchange and check the host key."},
+	"websocket": {description: "WebSocket checks, which perform the opening handshake."},
+	"file":      {description: "File checks, on a local path."},
+	"disk":      {description: "Disk checks, on the free space of a file system."},
+	"process":   {description: "Process checks, which look for a running process."},
+	"nagios":    {description: "Checks running a Nagios plugin."},
+
+	"probeConfig.initialDelay": {description: "How long after the prober started checks are first probed, they are pending until then."},
+	"initialDelay":             {description: "Overrides the global initial delay."},
+	"severity":                 {description: "A failing non-critical check degrades the prober without failing it.", enum: enum(severityCritical, severityNonCritical), def: severityCritical},
+	"dependsOn":                {description: "Checks that must succeed for this one to be evaluated, it is skipped otherwise."},
+	"flap":                     {description: "Enables flap detection."},
+	"breaker":                  {description: "Enables a circuit breaker."},
+	"retries":                  {description: "How many more times a failing check is probed within its timeout.", def: 0, min: bound(0)},
+	"backoff":                  {description: "The wait between the attempts of a check."},
+	"tags":                     {description: "Tags selecting the check with the tag and exclude query parameters of the endpoints."},
+	"extends":                  {description: "Templates the check takes its fields from, in order."},
+
+	"name":                   {description: "Unique name of the check."},
+	"timeout":                {description: "Bounds all the attempts of the check, 0 means no timeout."},
+	"ip":                     {description: "IP address to connect to."},
+	"host":                   {description: "Host to connect to."},
+	"port":                   {description: "Port to connect to.", min: bound(0), max: bound(65535)},
+	"url":                    {description: "URL to request."},
+	"webSocketService.url":   {description: "URL to connect to, with the ws or wss scheme."},
+	"header":                 {description: "Headers sent with the request."},
+	"httpHeader.name":        {description: "Header name."},
+	"value":                  {description: "Header value."},
+	"endpoints":              {description: "Endpoints of the etcd members."},
+	"maxRaftIndexLag":        {description: "Most raft index lag allowed between members, 0 disables the check."},
+	"ca":                     {description: "PEM file of the CA that signed the certificates of the etcd members."},
+	"cert":                   {description: "PEM file of the client certificate, set with key."},
+	"key":                    {description: "PEM file of the client key, set with cert."},
+	"servers":                {description: "ZooKeeper servers, as host:port."},
+	"maxOutstandingRequests": {description: "Most outstanding requests allowed, 0 disables the check."},
+	"startTLS":               {description: "Upgrades the connection with STARTTLS."},
+	"tls":                    {description: "Connects with TLS."},
+	"insecureSkipVerify":     {description: "Skips the verification of the server certificate."},
+	"user":                   {description: "User to log in as."},
+	"password":               {description: "Password to log in with."},
+	"hostKeyAlgorithm":       {description: "Host key algorithm to negotiate, any supported one by default.", enum: enum(sshprobe.HostKeyAlgorithms...)},
+	"fingerprint":            {description: "Expected SHA256 fingerprint of the host key."},
+	"subprotocol":            {description: "Subprotocol to request."},
+	"send":                   {description: "Text message sent once connected."},
+	"ping":                   {description: "Sends a ping once connected."},
+	"expect":                 {description: "Text the reply must contain."},
+	"path":                   {description: "Path to check."},
+	"readable":               {description: "The file must be readable."},
+	"writable":               {description: "The file must be writable."},
+	"maxAge":                 {description: "Oldest modification time allowed, 0 disables the check."},
+	"minFreePercent":         {description: "Least free space allowed, in percent.", min: bound(0), max: bound(100)},
+	"minFreeBytes":           {description: "Least free space allowed, in bytes."},
+	"minFreeInodesPercent":   {description: "Least free inodes allowed, in percent.", min: bound(0), max: bound(100)},
+	"minFreeInodes":          {description: "Least free inodes allowed."},
+	"command":                {description: "Name of the process."},
+	"cmdline":                {description: "Regular expression the command line of the process must match."},
+	"pidFile":                {description: "File holding the pid of the process."},
+	"maxRSS":                 {description: "Largest resident set size allowed, in bytes, 0 disables the check."},
+	"maxOpenFiles":           {description: "Most open files allowed, 0 disables the check."},
+	"cmd":                    {description: "Command and arguments of the Nagios plugin."},
+
+	"checkGroup.name": {description: "Unique name of the group."},
+	"checks":          {description: "Names of the checks of the group."},
+	"policy":          {description: "Whether all or any of the checks must succeed.", enum: enum(policyAll, policyAny), def: policyAll},
+	"atLeast":         {description: "How many checks must succeed, overrides policy.", min: bound(0)},
+	"percent":         {description: "Percentage of the checks that must succeed, overrides policy.", min: bound(0), max: bound(100)},
+
+	"window":        {description: "Number of recent results considered.", def: defaultFlapWindow, min: bound(3)},
+	"highThreshold": {description: "Percentage of state changes from which the check is flapping.", def: defaultFlapThreshold, min: bound(0), max: bound(100)},
+	"lowThreshold":  {description: "Percentage of state changes below which the check stops flapping, highThreshold by default.", min: bound(0), max: bound(100)},
+	"hold":          {description: "Reports the last stable result while the check is flapping."},
+
+	"failures": {description: "Consecutive failures that open the breaker.", def: defaultBreakerFailures, min: bound(0)},
+	"coolDown": {description: "How long the breaker stays open before a trial probe.", def: defaultBreakerCoolDown.String()},
+
+	"backoffOptions.policy": {description: "Exponential doubles the interval after every attempt up to maxInterval.", enum: enum(backoffConstant, backoffExponential), def: backoffConstant},
+	"interval":              {description: "Wait after the first attempt.", def: duration(defaultBackoffInterval).String()},
+	"maxInterval":           {description: "Longest wait of the exponential policy, 0 means no limit."},
+	"jitter":                {description: "Shortens every wait by a random fraction of at most jitter.", min: bound(0), max: bound(1)},
+}
+
+// Schema returns the JSON Schema of the config.
+func Schema() ([]byte, error) {
+	g := schemaGenerator{definitions: make(map[string]*jsonSchema)}
+	root := g.object(reflect.TypeOf(probeConfig{}))
+	root.Schema = "http://json-schema.org/draft-07/schema#"
+	root.Title = "service-prober config"
+	root.Definitions = g.definitions
+
+	// Checks may extend templates, and their defaults are checks of the
+	// same type whose fields are all optional.
+	defaults := make(map[string]*jsonSchema)
+	for kind := range g.definitions["service"].Properties {
+		check := g.definitions[checkType(kind).Name()]
+		check.Properties["extends"] = g.document("extends", &jsonSchema{
+			AnyOf: []*jsonSchema{{Type: "string"}, {Type: "array", Items: &jsonSchema{Type: "string"}}},
+		})
+		optional := *check
+		optional.Required = nil
+		defaults[kind] = &optional
+	}
+	root.Properties["defaults"] = g.document("defaults", &jsonSchema{
+		Type:                 "object",
+		Properties:           defaults,
+		AdditionalProperties: false,
+	})
+	root.Properties["templates"] = g.document("templates", &jsonSchema{
+		Type:                 "object",
+		AdditionalProperties: &jsonSchema{Type: "object"},
+	})
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGenerator struct {
+	definitions map[string]*jsonSchema
+}
+
+// document sets the description, enum, default and bounds of the field.
+func (g schemaGenerator) document(key string, s *jsonSchema) *jsonSchema {
+	field := fieldSchemas[key]
+	s.Description = field.description
+	s.Enum = field.enum
+	s.Default = field.def
+	if field.min != nil {
+		s.Minimum = field.min
+	}
+	if field.max != nil {
+		s.Maximum = field.max
+	}
+	return s
+}
+
+// schema returns the schema of a value of type t.
+func (g schemaGenerator) schema(t reflect.Type) *jsonSchema {
+	switch t {
+	case reflect.TypeOf(duration(0)):
+		return &jsonSchema{AnyOf: []*jsonSchema{
+			{Type: "string", Description: "A duration like 15s or 500ms, or seconds.", Pattern: `^([0-9]+(\.[0-9]*)?|([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`},
+			{Type: "number", Description: "Seconds.", Minimum: bound(0), ExclusiveMaximum: bound(maxSeconds)},
+		}}
+	case reflect.TypeOf(configString{}):
+		return &jsonSchema{AnyOf: []*jsonSchema{
+			{Type: "string", Description: "May reference environment variables as ${VAR} or ${VAR:-default}."},
+			{
+				Type:                 "object",
+				Properties:           map[string]*jsonSchema{"fromFile": {Type: "string", Description: "File the value is read from."}},
+				Required:             []string{"fromFile"},
+				AdditionalProperties: false,
+			},
+		}}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer", Minimum: bound(0)}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.object(t)
+		}
+		return &jsonSchema{Ref: "#/definitions/" + t.Name()}
+	}
+	return &jsonSchema{}
+}
+
+// object returns the schema of the struct t, whose fields are named as in
+// yaml configs.
+func (g schemaGenerator) object(t reflect.Type) *jsonSchema {
+	s := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+	g.fields(t, t, s)
+	if s.Properties["name"] != nil {
+		s.Required = []string{"name"}
+	}
+	return s
+}
+
+func (g schemaGenerator) fields(owner, t reflect.Type, s *jsonSchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			g.fields(owner, field.Type, s)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.ToLower(field.Name)
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+			name = tag
+		}
+		key := owner.Name() + "." + name
+		if _, ok := fieldSchemas[key]; !ok {
+			key = name
+		}
+		fs := g.schema(field.Type)
+		if fs.Ref != "" {
+			// Keywords next to $ref are ignored, so the reference is
+			// wrapped to be documented.
+			fs = &jsonSchema{AllOf: []*jsonSchema{fs}}
+		}
+		s.Properties[name] = g.document(key, fs)
+	}
+}
@@ -0,0 +1,60 @@
+package prober
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"concurrency", "deadline", "initialDelay", "startupTimeout", "service", "groups", "defaults", "templates"} {
+		if schema.Properties[key] == nil {
+			t.Errorf("expected property %s, get=%v", key, schema.Properties)
+		}
+	}
+	tcp := schema.Definitions["tcpService"]
+	if tcp == nil || !reflect.DeepEqual(tcp.Required, []string{"name"}) || tcp.AdditionalProperties != false {
+		t.Fatalf("expected tcpService to require a name, get=%+v", tcp)
+	}
+	expectedSeverity := []interface{}{severityCritical, severityNonCritical}
+	if severity := tcp.Properties["severity"]; !reflect.DeepEqual(severity.Enum, expectedSeverity) || severity.Default != severityCritical {
+		t.Errorf("expected severity enum=%v, get=%+v", expectedSeverity, severity)
+	}
+	if tcp.Properties["extends"] == nil || tcp.Properties["flap"].AllOf[0].Ref != "#/definitions/flapOptions" {
+		t.Errorf("expected extends and a documented flap reference, get=%+v", tcp.Properties)
+	}
+	if window := schema.Definitions["flapOptions"].Properties["window"]; window.Default != float64(defaultFlapWindow) {
+		t.Errorf("expected flap window default=%v, get=%v", defaultFlapWindow, window.Default)
+	}
+	if policy := schema.Definitions["backoffOptions"].Properties["policy"]; policy.Default != backoffConstant {
+		t.Errorf("expected backoff policy default=%v, get=%v", backoffConstant, policy.Default)
+	}
+	if policy := schema.Definitions["checkGroup"].Properties["policy"]; policy.Default != policyAll {
+		t.Errorf("expected group policy default=%v, get=%v", policyAll, policy.Default)
+	}
+	if defaults := schema.Properties["defaults"].Properties["tcp"]; defaults == nil || defaults.Required != nil {
+		t.Errorf("expected tcp defaults without required fields, get=%+v", defaults)
+	}
+}
+
+// Every field of the config must be documented in the schema.
+func TestSchemaDescriptions(t *testing.T) {
+	g := schemaGenerator{definitions: make(map[string]*jsonSchema)}
+	root := g.object(reflect.TypeOf(probeConfig{}))
+	g.definitions["probeConfig"] = root
+	for name, definition := range g.definitions {
+		for key, property := range definition.Properties {
+			if property.Description == "" {
+				t.Errorf("%s.%s: expected a description", name, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package prober
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// stdinConfig is the config name that reads the config from stdin.
+const stdinConfig = "-"
+
+// configSource reads the config from a file, stdin or an http(s) URL.
+type configSource struct {
+	name   string
+	stdin  io.Reader
+	client *http.Client
+	// etag and data are those of the last read.
+	etag string
+	data []byte
+}
+
+func newConfigSource(name string) *configSource {
+	return &configSource{
+		name:   name,
+		stdin:  os.Stdin,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *configSource) remote() bool {
+	return strings.HasPrefix(s.name, "http://") || strings.HasPrefix(s.name, "https://")
+}
+
+// read returns the config, and whether it changed since the last read.
+// Stdin is read once, URLs are fetched again only when their ETag changed.
+func (s *configSource) read() ([]byte, bool, error) {
+	var data []byte
+	var err error
+	switch {
+	case s.name == stdinConfig:
+		if s.data != nil {
+			return s.data, false, nil
+		}
+		data, err = ioutil.ReadAll(s.stdin)
+	case s.remote():
+		data, err = s.fetch()
+	default:
+		data, err = ioutil.ReadFile(s.name)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	changed := s.data == nil || !bytes.Equal(data, s.data)
+	s.data = data
+	return data, changed, nil
+}
+
+func (s *configSource) fetch() ([]byte, error) {
+	req, err := http.NewRequest("GET", s.name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.etag != "" && s.data != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return s.data, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("fetching config %s failed with statuscode: %d", s.name, res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.etag = res.Header.Get("ETag")
+	return data, nil
+}
+
+// path returns the path of the source, which tells the config type.
+func (s *configSource) path() string {
+	if s.remote() {
+		if u, err := url.Parse(s.name); err == nil {
+			return u.Path
+		}
+	}
+	return s.name
+}
+
+// detectConfigType tells JSON from YAML by the content of the config.
+func detectConfigType(data []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return "json"
+	}
+	return "yaml"
+}
+
+// configTypes returns the types the config read from the source may be of,
+// in the order decodeConfig tries them.
+func (s *configSource) configTypes(data []byte) []string {
+	var c probeConfig
+	if err := c.getConfigType(s.path()); err == nil {
+		return []string{c.configType}
+	}
+	if detectConfigType(data) == "json" {
+		return []string{"json"}
+	}
+	return []string{"yaml", "toml", "hcl"}
+}
+
+// parseConfig parses and validates the config read from the source.
+func (c *probeConfig) parseConfig(source *configSource, data []byte) error {
+	if err := c.decodeConfig(source, data); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+// decodeConfig parses the config read from the source. The type of the
+// config is told by its extension, or by its content when the extension is
+// unknown: JSON or YAML, then TOML or HCL when it is not YAML.
+func (c *probeConfig) decodeConfig(source *configSource, data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errors.New(source.name + ": config is empty")
+	}
+	if err := c.getConfigType(source.path()); err == nil {
+		return c.unmarshal(data)
+	}
+	original := *c
+	c.configType = detectConfigType(data)
+	err := c.unmarshal(data)
+	if err == nil || c.configType != "yaml" {
+		return err
+	}
+	// TOML and HCL are too close to YAML to be told by their content, the
+	// config is of the first of them that parses it.
+	for _, configType := range []string{"toml", "hcl"} {
+		if _, parseErr := toJSON(configType, data); parseErr != nil {
+			continue
+		}
+		*c = original
+		c.configType = configType
+		return c.unmarshal(data)
+	}
+	return fmt.Errorf("%v (toml and hcl configs are only told from yaml when they parse, name them .toml or .hcl)", err)
+}
+
+// handler serves the current prober, which is replaced when the config is
+// reloaded.
+type handler struct {
+	mu     sync.RWMutex
+	prober *prober
+}
+
+func (h *handler) current() *prober {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.prober
+}
+
+func (h *handler) serve(method func(*prober, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		method(h.current(), w, r)
+	}
+}
+
+// refresh reads the config again and replaces the prober when it changed.
+// The prober keeps serving the old config when the new one is invalid.
+func (h *handler) refresh(source *configSource) error {
+	data, changed, err := source.read()
+	if err != nil || !changed {
+		return err
+	}
+	c := &probeConfig{}
+	if err := c.parseConfig(source, data); err != nil {
+		return err
+	}
+	p := newProber(c)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p.inherit(h.prober)
+	h.prober = p
+	glog.Info("config reloaded from ", source.name)
+	return nil
+}
+
+// watch refreshes the config every interval.
+func (h *handler) watch(source *configSource, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := h.refresh(source); err != nil {
+			glog.Error(h.current().redact(err.Error()))
+		}
+	}
+}
+
+// inherit carries the startup of the old prober over, so that a reload
+// neither delays checks again nor restarts the startup probe.
+func (p *prober) inherit(old *prober) {
+	p.started = old.started
+	if status, _ := old.startup.status(); status == statusOK {
+		p.startup.started = true
+	}
+}
@@ -0,0 +1,165 @@
+package prober
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const jsonConfig = `{"service": {"tcp": [{"name": "casandra", "ip": "127.0.0.1", "port": 9042, "timeout": "15s"}]}}`
+
+const yamlConfig = `
+service:
+  tcp:
+  - name: casandra
+    ip: 127.0.0.1
+    port: 9042
+    timeout: 15s
+`
+
+const tomlConfig = `
+[[service.tcp]]
+name = "casandra"
+ip = "127.0.0.1"
+port = 9042
+timeout = "15s"
+`
+
+const hclConfig = `
+service {
+  tcp {
+    name = "casandra"
+    ip = "127.0.0.1"
+    port = 9042
+    timeout = "15s"
+  }
+}
+`
+
+func TestDetectConfigType(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{jsonConfig, "json"},
+		{"\n  " + jsonConfig, "json"},
+		{yamlConfig, "yaml"},
+		{"---" + yamlConfig, "yaml"},
+	}
+	for i, tt := range tests {
+		if configType := detectConfigType([]byte(tt.data)); configType != tt.expected {
+			t.Errorf("#%d: expected result=%v, get=%v", i, tt.expected, configType)
+		}
+	}
+}
+
+func TestReadConfigWithoutExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service-prober")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, data := range []string{jsonConfig, yamlConfig, tomlConfig, hclConfig} {
+		for _, name := range []string{"probes", "config.conf"} {
+			path := filepath.Join(dir, name)
+			ioutil.WriteFile(path, []byte(data), 0600)
+			c := probeConfig{}
+			if err := c.readConfig(newConfigSource(path), ""); err != nil {
+				t.Errorf("#%d: %s: unexpected error: %v", i, name, err)
+				continue
+			}
+			if len(c.Service.TCP) != 1 || c.Service.TCP[0].Name != "casandra" {
+				t.Errorf("#%d: %s: expected the casandra check, get=%+v", i, name, c.Service)
+			}
+		}
+	}
+}
+
+func TestDecodeConfigWithoutExtension(t *testing.T) {
+	tests := []struct {
+		data          string
+		expectedError string
+	}{
+		{"[[service.tcp]]\nname = \"casandra\"\nextends = \"internal\"\n", "casandra: unknown template internal"},
+		{"[service\nname = \"casandra\"\n", "name them .toml or .hcl"},
+		{"service:\n  tcp: 1\n", "name them .toml or .hcl"},
+	}
+	for i, tt := range tests {
+		c := probeConfig{}
+		err := c.decodeConfig(newConfigSource("probes"), []byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+func TestConfigSourceStdin(t *testing.T) {
+	source := newConfigSource(stdinConfig)
+	source.stdin = strings.NewReader(yamlConfig)
+	for i, expectedChanged := range []bool{true, false} {
+		data, changed, err := source.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != yamlConfig || changed != expectedChanged {
+			t.Errorf("#%d: expected changed=%v, get changed=%v data=%q", i, expectedChanged, changed, data)
+		}
+	}
+}
+
+func TestConfigSourceURL(t *testing.T) {
+	config := jsonConfig
+	etag := `"v1"`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(config))
+	}))
+	defer server.Close()
+
+	source := newConfigSource(server.URL + "/probes")
+	c := &probeConfig{}
+	data, _, err := source.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.parseConfig(source, data); err != nil {
+		t.Fatal(err)
+	}
+	h := &handler{prober: newProber(c)}
+
+	tests := []struct {
+		config        string
+		etag          string
+		expectedCheck string
+		expectedError string
+	}{
+		// Not modified, the config is kept.
+		{jsonConfig, `"v1"`, "casandra", ""},
+		{strings.Replace(yamlConfig, "casandra", "cassandra", 1), `"v2"`, "cassandra", ""},
+		// An invalid config is not served.
+		{`{"service": {"tcp": [{"name": ""}]}}`, `"v3"`, "cassandra", "tcp: check name is empty"},
+	}
+	for i, tt := range tests {
+		config, etag = tt.config, tt.etag
+		err := h.refresh(source)
+		if (err == nil && tt.expectedError != "") || (err != nil && err.Error() != tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+		if name := h.current().config.Service.TCP[0].Name; name != tt.expectedCheck {
+			t.Errorf("#%d: expected check=%s, get=%s", i, tt.expectedCheck, name)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("expected 4 requests, get=%d", requests)
+	}
+}
@@ -0,0 +1,124 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+// pending is the result of a check that is not probed yet because of its
+// initial delay.
+const pending probe.Result = "pending"
+
+// statusStarting is the status of the prober until every check has passed
+// once.
+const statusStarting = "STARTING"
+
+// startup tracks which checks have passed at least once since the prober
+// started, like the kubelet's startupProbe. Once every check has passed the
+// prober is started for good.
+type startup struct {
+	mu      sync.Mutex
+	begin   time.Time
+	timeout time.Duration
+	names   []string
+	passed  map[string]bool
+	started bool
+}
+
+func newStartup(names []string, timeout time.Duration) *startup {
+	return &startup{
+		begin:   time.Now(),
+		timeout: timeout,
+		names:   names,
+		passed:  make(map[string]bool),
+	}
+}
+
+// record marks the checks that succeeded in results as passed.
+func (s *startup) record(results []checkResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	for _, result := range results {
+		if result.Result == probe.Success || result.Result == warning {
+			s.passed[result.Name] = true
+		}
+	}
+	s.started = len(s.waiting()) == 0
+}
+
+// waiting returns the checks that have not passed yet.
+func (s *startup) waiting() []string {
+	var names []string
+	for _, name := range s.names {
+		if !s.passed[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// status returns statusOK once every check has passed, statusStarting until
+// then and statusFail when the startup timeout, if any, has expired. It
+// also returns the checks that have not passed yet.
+func (s *startup) status() (string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.started:
+		return statusOK, nil
+	case s.timeout > 0 && time.Since(s.begin) > s.timeout:
+		return statusFail, s.waiting()
+	}
+	return statusStarting, s.waiting()
+}
+
+// startupResponse is the JSON body of the startup endpoint.
+type startupResponse struct {
+	Status  string        `json:"status"`
+	Waiting []string      `json:"waiting,omitempty"`
+	Checks  []checkResult `json:"checks,omitempty"`
+}
+
+// initialDelay returns how long after the prober started the check is
+// first probed, the check's own delay taking precedence.
+func (p *prober) initialDelay(c check) time.Duration {
+	if c.options.InitialDelay != nil {
+		return time.Duration(*c.options.InitialDelay)
+	}
+	return time.Duration(p.config.InitialDelay)
+}
+
+// startupProbe succeeds once every check has passed once. Checks are not
+// evaluated anymore after that.
+func (p *prober) startupProbe(w http.ResponseWriter, r *http.Request) {
+	status, waiting := p.startup.status()
+	var results []checkResult
+	if status != statusOK {
+		results = p.evaluate(r.Context())
+		status, waiting = p.startup.status()
+	}
+	code := http.StatusOK
+	if status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	if wantsJSON(r) {
+		writeJSON(w, code, startupResponse{Status: status, Waiting: waiting, Checks: results})
+		return
+	}
+	switch status {
+	case statusOK:
+		w.Write([]byte("OK"))
+	case statusStarting:
+		http.Error(w, statusStarting+"\nwaiting for: "+strings.Join(waiting, ", "), code)
+	default:
+		http.Error(w, fmt.Sprintf("startup timeout after %v, never passed: %s", p.startup.timeout, strings.Join(waiting, ", ")), code)
+	}
+}
@@ -0,0 +1,108 @@
+package prober
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/probe"
+)
+
+func durationPtr(d time.Duration) *duration {
+	value := duration(d)
+	return &value
+}
+
+func TestInitialDelay(t *testing.T) {
+	p := newProber(&probeConfig{
+		InitialDelay: duration(time.Hour),
+		Service: service{
+			TCP: []tcpService{
+				{Name: "db", IP: "10.0.0.1"},
+				{Name: "api", IP: "10.0.0.2", checkOptions: checkOptions{DependsOn: []string{"db"}}},
+				{Name: "cache", IP: "10.0.0.3", checkOptions: checkOptions{InitialDelay: durationPtr(time.Nanosecond)}},
+				{Name: "queue", IP: "10.0.0.4", checkOptions: checkOptions{InitialDelay: durationPtr(0)}},
+			},
+		},
+	})
+	p.tcpProber = fakeHostTCPProber{map[string]bool{"10.0.0.1": true, "10.0.0.3": true}}
+	expected := []checkResult{
+		{Name: "db", Result: pending, Output: "initial delay, probing in 1h0m0s"},
+		{Name: "api", Result: pending, Output: "initial delay, probing in 1h0m0s"},
+		{Name: "cache", Result: probe.Failure, Output: "connection refused"},
+		{Name: "queue", Result: probe.Success},
+	}
+	results := p.evaluate(context.Background())
+	for i, result := range results {
+		if result.Name != expected[i].Name || result.Result != expected[i].Result || result.Output != expected[i].Output {
+			t.Errorf("#%d: expected %s result=%v output=%q, get %s result=%v output=%q", i,
+				expected[i].Name, expected[i].Result, expected[i].Output, result.Name, result.Result, result.Output)
+		}
+	}
+	if status, _ := p.summarize(results); status != statusFail {
+		t.Errorf("expected status=%s, get=%s", statusFail, status)
+	}
+}
+
+func get(handler http.HandlerFunc) (int, string) {
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+func TestStartupProbe(t *testing.T) {
+	down := map[string]bool{"10.0.0.1": true}
+	p := newProber(&probeConfig{
+		StartupTimeout: duration(time.Hour),
+		Service:        service{TCP: []tcpService{{Name: "db", IP: "10.0.0.1"}, {Name: "cache", IP: "10.0.0.2"}}},
+	})
+	p.tcpProber = fakeHostTCPProber{down}
+	tests := []struct {
+		down           bool
+		handler        http.HandlerFunc
+		expectedCode   int
+		expectedResult string
+	}{
+		{true, p.startupProbe, http.StatusServiceUnavailable, "STARTING\nwaiting for: db\n"},
+		{true, p.liveness, http.StatusOK, "STARTING\ndb connection refused\n"},
+		{false, p.startupProbe, http.StatusOK, "OK"},
+		// The prober stays started once every check has passed.
+		{true, p.startupProbe, http.StatusOK, "OK"},
+		{true, p.liveness, http.StatusServiceUnavailable, "db connection refused\n\n"},
+	}
+	for i, tt := range tests {
+		down["10.0.0.1"] = tt.down
+		code, body := get(tt.handler)
+		if code != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, code)
+		}
+		if body != tt.expectedResult {
+			t.Errorf("#%d: expected result=%q, get=%q", i, tt.expectedResult, body)
+		}
+	}
+}
+
+func TestStartupTimeout(t *testing.T) {
+	p := newProber(&probeConfig{
+		StartupTimeout: duration(time.Nanosecond),
+		Service:        service{TCP: []tcpService{{Name: "db", IP: "10.0.0.1"}}},
+	})
+	p.tcpProber = fakeHostTCPProber{map[string]bool{"10.0.0.1": true}}
+	code, body := get(p.startupProbe)
+	if code != http.StatusServiceUnavailable || body != "startup timeout after 1ns, never passed: db\n" {
+		t.Errorf("expected code=%d result=%q, get code=%d result=%q", http.StatusServiceUnavailable, "startup timeout after 1ns, never passed: db\n", code, body)
+	}
+	code, _ = get(p.liveness)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected code=%d, get=%d", http.StatusServiceUnavailable, code)
+	}
+}
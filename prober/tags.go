@@ -0,0 +1,108 @@
+package prober
+
+import (
+	"net/http"
+	"strings"
+)
+
+// tagFilter selects the checks carrying any of the tags, all checks when
+// tags is empty, and none of the excluded tags.
+type tagFilter struct {
+	tags    []string
+	exclude []string
+}
+
+// newTagFilter reads the tag and exclude query parameters, which may be
+// repeated or comma separated.
+func newTagFilter(r *http.Request) tagFilter {
+	split := func(values []string) []string {
+		var tags []string
+		for _, value := range values {
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		return tags
+	}
+	query := r.URL.Query()
+	return tagFilter{tags: split(query["tag"]), exclude: split(query["exclude"])}
+}
+
+func (f tagFilter) empty() bool {
+	return len(f.tags) == 0 && len(f.exclude) == 0
+}
+
+func (f tagFilter) match(tags []string) bool {
+	for _, tag := range f.exclude {
+		if contains(tags, tag) {
+			return false
+		}
+	}
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, tag := range f.tags {
+		if contains(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// selected returns the names of the checks and groups matching the filter.
+// The checks of a matching group are selected with it.
+func (p *prober) selected(f tagFilter) map[string]bool {
+	names := make(map[string]bool)
+	for _, c := range p.checks() {
+		if f.match(c.options.Tags) {
+			names[c.name] = true
+		}
+	}
+	for _, g := range p.config.Groups {
+		if !f.match(g.Tags) {
+			continue
+		}
+		names[g.Name] = true
+		for _, name := range g.Checks {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// evaluateRequest evaluates the checks selected by the tag filter of the
+// request, and their dependencies, and returns the results of the selected
+// checks.
+func (p *prober) evaluateRequest(r *http.Request) []checkResult {
+	f := newTagFilter(r)
+	if f.empty() {
+		return p.evaluate(r.Context())
+	}
+	names := p.selected(f)
+	var selected []string
+	for name := range names {
+		selected = append(selected, name)
+	}
+	results := p.evaluateChecks(r.Context(), withDependencies(p.checks(), selected))
+	return filterResults(results, names)
+}
+
+// filterResults keeps the results with the given names. The checks of a
+// group left out are reported on their own.
+func filterResults(results []checkResult, names map[string]bool) []checkResult {
+	var filtered []checkResult
+	for _, result := range results {
+		if names[result.Name] {
+			filtered = append(filtered, result)
+			continue
+		}
+		for _, member := range result.Members {
+			if names[member.Name] {
+				filtered = append(filtered, member)
+			}
+		}
+	}
+	return filtered
+}
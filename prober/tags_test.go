@@ -0,0 +1,83 @@
+package prober
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTagFilter(t *testing.T) {
+	tests := []struct {
+		query    string
+		tags     []string
+		expected bool
+	}{
+		{"", nil, true},
+		{"tag=db", []string{"db", "external"}, true},
+		{"tag=db", []string{"cache"}, false},
+		{"tag=db&tag=cache", []string{"cache"}, true},
+		{"tag=db,cache", []string{"cache"}, true},
+		{"exclude=external", []string{"db", "external"}, false},
+		{"exclude=external", nil, true},
+		{"tag=db&exclude=external", []string{"db"}, true},
+		{"tag=db&exclude=external", []string{"db", "external"}, false},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest("GET", "/liveness?"+tt.query, nil)
+		if match := newTagFilter(r).match(tt.tags); match != tt.expected {
+			t.Errorf("#%d: expected match=%v, get=%v", i, tt.expected, match)
+		}
+	}
+}
+
+func TestLivenessTags(t *testing.T) {
+	p := newProber(&probeConfig{
+		Service: service{
+			TCP: []tcpService{
+				{Name: "network", IP: "10.0.0.1"},
+				{Name: "mongo", IP: "10.0.0.2", checkOptions: checkOptions{Tags: []string{"db"}, DependsOn: []string{"network"}}},
+				{Name: "redis", IP: "10.0.0.3", checkOptions: checkOptions{Tags: []string{"cache"}}},
+				{Name: "s3", IP: "10.0.0.4", checkOptions: checkOptions{Tags: []string{"external"}}},
+				{Name: "cassandra-1", IP: "10.0.0.5"},
+				{Name: "cassandra-2", IP: "10.0.0.6"},
+			},
+		},
+		Groups: []checkGroup{{Name: "cassandra", Checks: []string{"cassandra-1", "cassandra-2"}, checkOptions: checkOptions{Tags: []string{"db"}}}},
+	})
+	p.tcpProber = fakeHostTCPProber{map[string]bool{"10.0.0.1": true, "10.0.0.4": true}}
+	tests := []struct {
+		query          string
+		expectedCode   int
+		expectedResult string
+		expectedChecks []string
+	}{
+		{"tag=cache", http.StatusOK, "OK", []string{"redis"}},
+		{"tag=db", http.StatusServiceUnavailable, "mongo skipped, dependency network failure: connection refused\n\n", []string{"mongo", "cassandra"}},
+		{"exclude=external&exclude=db", http.StatusServiceUnavailable, "network connection refused\n\n", []string{"network", "redis", "cassandra-1", "cassandra-2"}},
+	}
+	for i, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(p.liveness))
+		res, err := http.Get(ts.URL + "?" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		ts.Close()
+		if res.StatusCode != tt.expectedCode {
+			t.Errorf("#%d: expected code=%d, get=%d", i, tt.expectedCode, res.StatusCode)
+		}
+		if string(body) != tt.expectedResult {
+			t.Errorf("#%d: expected result=%q, get=%q", i, tt.expectedResult, body)
+		}
+		var names []string
+		for _, result := range p.evaluateRequest(httptest.NewRequest("GET", "/liveness?"+tt.query, nil)) {
+			names = append(names, result.Name)
+		}
+		if !reflect.DeepEqual(names, tt.expectedChecks) {
+			t.Errorf("#%d: expected checks=%v, get=%v", i, tt.expectedChecks, names)
+		}
+	}
+}
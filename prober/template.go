@@ -0,0 +1,450 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// expandTemplates applies the defaults and templates of the config to its
+// checks, before the config is decoded. Defaults are set per check type,
+// and checks name the templates they extend, which may extend others:
+//
+//	defaults:
+//	  http:
+//	    timeout: 5s
+//	templates:
+//	  internal:
+//	    severity: non-critical
+//	service:
+//	  http:
+//	  - name: mongo
+//	    extends: internal
+//
+// A check takes the defaults of its type, then its templates in order, then
+// its own fields. Fields set later override those set before, objects are
+// merged field by field and lists are replaced. When shared is not nil it
+// holds the defaults and templates of every file of the config, which
+// replace those of this file. The config is returned as is when there are
+// no defaults or templates and no check extends one.
+func expandTemplates(configType string, configFile []byte, shared *configTemplates) ([]byte, error) {
+	root, err := parseConfigObject(configType, configFile)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return configFile, nil
+	}
+	_, hasDefaults := root["defaults"]
+	_, hasTemplates := root["templates"]
+	templates := shared
+	if templates == nil {
+		templates = &configTemplates{}
+		if err := templates.add(root, ""); err != nil {
+			return nil, err
+		}
+	}
+	delete(root, "defaults")
+	delete(root, "templates")
+	services, _ := asObject(root["service"])
+	if !hasDefaults && !hasTemplates && len(templates.defaults) == 0 && !extendsTemplates(services) {
+		return configFile, nil
+	}
+	if err := templates.expand(services); err != nil {
+		return nil, err
+	}
+
+	if configType == "yaml" {
+		return yaml.Marshal(root)
+	}
+	return json.Marshal(root)
+}
+
+// parseConfigObject parses the config into string keyed maps, or returns
+// nil when it is not an object.
+func parseConfigObject(configType string, configFile []byte) (map[string]interface{}, error) {
+	if configType == "toml" || configType == "hcl" {
+		var err error
+		if configFile, err = toJSON(configType, configFile); err != nil {
+			return nil, err
+		}
+		configType = "json"
+	}
+	var doc interface{}
+	var err error
+	switch configType {
+	case "yaml":
+		err = yaml.Unmarshal(configFile, &doc)
+	case "json":
+		err = json.Unmarshal(configFile, &doc)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	root, ok := normalize(doc).(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if configType == "json" {
+		if err := canonicalizeConfig(root); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// configTemplates holds the defaults and templates of a config, which apply
+// to the checks of every file of the config. The defaults of files read
+// later override those read before.
+type configTemplates struct {
+	defaults  map[string]map[string]interface{}
+	templates map[string]interface{}
+	// sources maps the templates to the files they are defined in.
+	sources map[string]string
+}
+
+// collect adds the defaults and templates of the config file, parsed as the
+// first of the types it is an object of. A file that does not parse is left
+// for its decoding to report.
+func (t *configTemplates) collect(configTypes []string, configFile []byte, file string) error {
+	for _, configType := range configTypes {
+		if root, err := parseConfigObject(configType, configFile); err == nil && root != nil {
+			return t.add(root, file)
+		}
+	}
+	return nil
+}
+
+// add adds the defaults and templates of the config read from file.
+func (t *configTemplates) add(root map[string]interface{}, file string) error {
+	defaults, ok := asObject(root["defaults"])
+	if !ok {
+		return errors.New("defaults must map check types to fields")
+	}
+	templates, ok := asObject(root["templates"])
+	if !ok {
+		return errors.New("templates must map names to fields")
+	}
+	if t.defaults == nil {
+		t.defaults = make(map[string]map[string]interface{})
+		t.templates = make(map[string]interface{})
+		t.sources = make(map[string]string)
+	}
+	kinds := make(map[string]bool, len(defaults))
+	for kind, fields := range defaults {
+		if !isCheckType(kind) {
+			return errors.New("defaults for unknown check type " + kind)
+		}
+		kind = strings.ToLower(kind)
+		if kinds[kind] {
+			return errors.New("defaults for " + kind + " are set twice")
+		}
+		kinds[kind] = true
+		fields, ok := fields.(map[string]interface{})
+		if !ok {
+			return errors.New("defaults of " + kind + " must be fields")
+		}
+		if t.defaults[kind] == nil {
+			t.defaults[kind] = make(map[string]interface{})
+		}
+		merge(t.defaults[kind], fields)
+	}
+	for name, fields := range templates {
+		if other, ok := t.sources[name]; ok {
+			return fmt.Errorf("template %s is defined in %s and %s", name, fileLabel(other), fileLabel(file))
+		}
+		t.templates[name] = fields
+		t.sources[name] = file
+	}
+	return nil
+}
+
+// expand applies the defaults and templates to the checks of the services.
+func (t *configTemplates) expand(services map[string]interface{}) error {
+	for kind, checks := range services {
+		list, _ := checks.([]interface{})
+		for i, check := range list {
+			fields, ok := check.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			expanded := make(map[string]interface{})
+			if d, ok := t.defaults[strings.ToLower(kind)]; ok {
+				merge(expanded, d)
+			}
+			if err := extend(expanded, fields, t.templates, nil); err != nil {
+				return fmt.Errorf("%v: %v", fields["name"], err)
+			}
+			list[i] = expanded
+		}
+	}
+	return nil
+}
+
+// fileLabel names the file for errors, the config file being read first.
+func fileLabel(file string) string {
+	if file == "" {
+		return "the config file"
+	}
+	return file
+}
+
+// extend merges the templates fields extends, then the fields themselves,
+// into expanded. chain holds the templates being expanded, to detect cycles.
+func extend(expanded, fields map[string]interface{}, templates map[string]interface{}, chain []string) error {
+	var names []string
+	switch extends := fields["extends"].(type) {
+	case nil:
+	case string:
+		names = []string{extends}
+	case []interface{}:
+		for _, name := range extends {
+			s, ok := name.(string)
+			if !ok {
+				return errors.New("extends must name templates")
+			}
+			names = append(names, s)
+		}
+	default:
+		return errors.New("extends must name templates")
+	}
+	for _, name := range names {
+		if contains(chain, name) {
+			return errors.New("template cycle: " + strings.Join(append(chain, name), " -> "))
+		}
+		template, ok := templates[name].(map[string]interface{})
+		if !ok {
+			return errors.New("unknown template " + name)
+		}
+		if err := extend(expanded, template, templates, append(chain, name)); err != nil {
+			return err
+		}
+	}
+	own := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if key != "extends" {
+			own[key] = value
+		}
+	}
+	merge(expanded, own)
+	return nil
+}
+
+// merge sets the fields of src in dst, merging objects field by field.
+func merge(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if object, ok := value.(map[string]interface{}); ok {
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				merged := make(map[string]interface{}, len(existing))
+				merge(merged, existing)
+				merge(merged, object)
+				dst[key] = merged
+				continue
+			}
+			copied := make(map[string]interface{}, len(object))
+			merge(copied, object)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// extendsTemplates reports whether any check of the services extends a
+// template.
+func extendsTemplates(services map[string]interface{}) bool {
+	for _, checks := range services {
+		list, _ := checks.([]interface{})
+		for _, check := range list {
+			if fields, ok := check.(map[string]interface{}); ok {
+				if _, ok := fields["extends"]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// canonicalizeConfig renames the keys of the config decoded from JSON to
+// the config keys of the fields they set, as JSON decoding matches keys
+// regardless of case, so that a check's "timeOut" overrides the "timeout"
+// of its defaults instead of being merged as another key.
+func canonicalizeConfig(root map[string]interface{}) error {
+	return renameKeys(root, func(key string, value interface{}) (string, interface{}, error) {
+		switch strings.ToLower(key) {
+		case "defaults":
+			defaults, ok := value.(map[string]interface{})
+			if !ok {
+				return "defaults", value, nil
+			}
+			err := renameKeys(defaults, func(kind string, fields interface{}) (string, interface{}, error) {
+				name, t := configField(reflect.TypeOf(service{}), kind)
+				if t == nil {
+					return kind, fields, nil
+				}
+				fields, err := canonicalize(fields, t.Elem())
+				return name, fields, err
+			})
+			return "defaults", defaults, err
+		case "templates":
+			templates, ok := value.(map[string]interface{})
+			if !ok {
+				return "templates", value, nil
+			}
+			for name, fields := range templates {
+				fields, ok := fields.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				err := renameKeys(fields, func(key string, value interface{}) (string, interface{}, error) {
+					name, t := templateField(key)
+					if t == nil {
+						return canonicalExtends(key), value, nil
+					}
+					value, err := canonicalize(value, t)
+					return name, value, err
+				})
+				if err != nil {
+					return "", nil, fmt.Errorf("template %s: %v", name, err)
+				}
+			}
+			return "templates", templates, nil
+		}
+		name, t := configField(reflect.TypeOf(probeConfig{}), key)
+		if t == nil {
+			return key, value, nil
+		}
+		value, err := canonicalize(value, t)
+		return name, value, err
+	})
+}
+
+// canonicalize renames the keys of the value decoded from JSON after the
+// type t. Unknown keys and values decoded by the type itself are left as is.
+func canonicalize(value interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			err := renameKeys(v, func(key string, value interface{}) (string, interface{}, error) {
+				name, ft := configField(t, key)
+				if ft == nil {
+					return canonicalExtends(key), value, nil
+				}
+				value, err := canonicalize(value, ft)
+				return name, value, err
+			})
+			return v, err
+		case reflect.Map:
+			for key, value := range v {
+				value, err := canonicalize(value, t.Elem())
+				if err != nil {
+					return nil, err
+				}
+				v[key] = value
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, value := range v {
+				value, err := canonicalize(value, t.Elem())
+				if err != nil {
+					return nil, err
+				}
+				v[i] = value
+			}
+		}
+	}
+	return value, nil
+}
+
+// canonicalExtends returns the key of the extends of a check, which is not a
+// field of the checks, for key, or key itself.
+func canonicalExtends(key string) string {
+	if strings.EqualFold(key, "extends") {
+		return "extends"
+	}
+	return key
+}
+
+// renameKeys replaces the keys and values of object by those rename returns
+// for them, failing when two keys are renamed alike.
+func renameKeys(object map[string]interface{}, rename func(string, interface{}) (string, interface{}, error)) error {
+	keys := make(map[string]string, len(object))
+	renamed := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		name, value, err := rename(key, value)
+		if err != nil {
+			return err
+		}
+		if other, ok := keys[name]; ok {
+			if other > key {
+				other, key = key, other
+			}
+			return fmt.Errorf("%s and %s set the same field", other, key)
+		}
+		keys[name] = key
+		renamed[name] = value
+	}
+	for key := range object {
+		delete(object, key)
+	}
+	for name, value := range renamed {
+		object[name] = value
+	}
+	return nil
+}
+
+// isCheckType reports whether kind names a list of the service block.
+func isCheckType(kind string) bool {
+	_, ok := reflect.TypeOf(service{}).FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, kind)
+	})
+	return ok
+}
+
+// normalize turns the objects decoded from YAML into string keyed maps,
+// like those decoded from JSON.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			object[fmt.Sprint(key)] = normalize(value)
+		}
+		return object
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	}
+	return value
+}
+
+// asObject returns value as an object, a missing value being empty.
+func asObject(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return map[string]interface{}{}, true
+	}
+	object, ok := value.(map[string]interface{})
+	return object, ok
+}
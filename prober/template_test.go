@@ -0,0 +1,174 @@
+package prober
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandTemplates(t *testing.T) {
+	yamlFile := `
+defaults:
+  http:
+    timeout: 5s
+    header:
+    - name: Authorization
+      value: token
+  file:
+    readable: true
+templates:
+  internal:
+    severity: non-critical
+    flap:
+      window: 20
+  slow:
+    extends: internal
+    timeout: 30s
+    retries: 2
+service:
+  http:
+  - name: api
+    url: http://127.0.0.1:8080
+    extends: slow
+    flap:
+      highThreshold: 80
+  - name: web
+    url: http://127.0.0.1:80
+    header: []
+  file:
+  - name: lock
+    path: /var/run/lock
+    readable: false
+  tcp:
+  - name: casandra
+    ip: 127.0.0.1
+    port: 9042
+    extends: [internal]
+`
+	jsonFile := `{
+  "defaults": {"http": {"timeout": "5s", "header": [{"name": "Authorization", "value": "token"}]}, "file": {"readable": true}},
+  "templates": {
+    "internal": {"severity": "non-critical", "flap": {"window": 20}},
+    "slow": {"extends": "internal", "timeout": "30s", "retries": 2}
+  },
+  "service": {
+    "http": [
+      {"name": "api", "url": "http://127.0.0.1:8080", "extends": "slow", "flap": {"highThreshold": 80}},
+      {"name": "web", "url": "http://127.0.0.1:80", "header": []}
+    ],
+    "file": [{"name": "lock", "path": "/var/run/lock", "readable": false}],
+    "tcp": [{"name": "casandra", "ip": "127.0.0.1", "port": 9042, "extends": ["internal"]}]
+  }
+}`
+	for _, configType := range []string{"yaml", "json"} {
+		configFile := yamlFile
+		if configType == "json" {
+			configFile = jsonFile
+		}
+		c := probeConfig{configType: configType}
+		if err := c.convertDataToStruct([]byte(configFile)); err != nil {
+			t.Fatalf("%s: %v", configType, err)
+		}
+		api, web := c.Service.HTTP[0], c.Service.HTTP[1]
+		if api.TimeOut != duration(30*time.Second) || api.Retries != 2 || api.Severity != severityNonCritical {
+			t.Errorf("%s: expected api to extend slow, get=%+v", configType, api)
+		}
+		if api.Flap == nil || api.Flap.Window != 20 || api.Flap.HighThreshold != 80 {
+			t.Errorf("%s: expected api flap merged field by field, get=%+v", configType, api.Flap)
+		}
+		expectedHeader := []httpHeader{{Name: "Authorization", Value: configString{value: "token"}}}
+		if !reflect.DeepEqual(api.Header, expectedHeader) {
+			t.Errorf("%s: expected api header=%v, get=%v", configType, expectedHeader, api.Header)
+		}
+		if web.TimeOut != duration(5*time.Second) || len(web.Header) != 0 || web.Severity != "" {
+			t.Errorf("%s: expected web to take the defaults and replace the header, get=%+v", configType, web)
+		}
+		if c.Service.File[0].Readable {
+			t.Errorf("%s: expected lock to override readable, get=%+v", configType, c.Service.File[0])
+		}
+		casandra := c.Service.TCP[0]
+		if casandra.Severity != severityNonCritical || casandra.TimeOut != 0 {
+			t.Errorf("%s: expected casandra to extend internal only, get=%+v", configType, casandra)
+		}
+	}
+}
+
+func TestExpandTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		configFile    string
+		expectedError string
+	}{
+		{"templates: {}\nservice:\n  tcp:\n  - name: casandra\n    extends: internal\n", "casandra: unknown template internal"},
+		{"service:\n  tcp:\n  - name: casandra\n    extends: internal\n", "casandra: unknown template internal"},
+		{"templates:\n  a: {extends: b}\n  b: {extends: a}\nservice:\n  tcp:\n  - name: casandra\n    extends: a\n", "casandra: template cycle: a -> b -> a"},
+		{"templates: {}\nservice:\n  tcp:\n  - name: casandra\n    extends: {name: a}\n", "casandra: extends must name templates"},
+		{"defaults:\n  ldap: {timeout: 1s}\n", "defaults for unknown check type ldap"},
+		{"defaults: [tcp]\n", "defaults must map check types to fields"},
+		{"templates:\n  internal:\n    severity: low\nservice:\n  tcp:\n  - name: casandra\n    extends: internal\n", "casandra: severity must be critical or non-critical"},
+	}
+	for i, tt := range tests {
+		c := probeConfig{configType: "yaml"}
+		err := c.convertDataToStruct([]byte(tt.configFile))
+		if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+		}
+	}
+}
+
+func TestExpandTemplatesKeyCase(t *testing.T) {
+	tests := []struct {
+		configType      string
+		configFile      string
+		expectedTimeout duration
+		expectedError   string
+	}{
+		{
+			configType:      "json",
+			configFile:      `{"defaults": {"http": {"timeout": "5s"}}, "service": {"http": [{"name": "api", "url": "http://127.0.0.1", "timeOut": "1s"}]}}`,
+			expectedTimeout: duration(time.Second),
+		},
+		{
+			configType:      "json",
+			configFile:      `{"Defaults": {"HTTP": {"TimeOut": "5s"}}, "Service": {"Http": [{"name": "api", "url": "http://127.0.0.1"}]}}`,
+			expectedTimeout: duration(5 * time.Second),
+		},
+		{
+			configType:      "json",
+			configFile:      `{"templates": {"slow": {"TIMEOUT": "30s"}}, "service": {"http": [{"name": "api", "url": "http://127.0.0.1", "Extends": "slow"}]}}`,
+			expectedTimeout: duration(30 * time.Second),
+		},
+		{
+			configType:      "yaml",
+			configFile:      "defaults:\n  HTTP:\n    timeout: 5s\nservice:\n  http:\n  - name: api\n    url: http://127.0.0.1\n",
+			expectedTimeout: duration(5 * time.Second),
+		},
+		{
+			configType:    "json",
+			configFile:    `{"defaults": {}, "service": {"http": [{"name": "api", "url": "http://127.0.0.1", "timeout": "5s", "timeOut": "1s"}]}}`,
+			expectedError: "timeOut and timeout set the same field",
+		},
+		{
+			configType:    "yaml",
+			configFile:    "defaults:\n  http: {timeout: 1s}\n  HTTP: {timeout: 5s}\n",
+			expectedError: "defaults for http are set twice",
+		},
+	}
+	for i, tt := range tests {
+		c := probeConfig{configType: tt.configType}
+		err := c.convertDataToStruct([]byte(tt.configFile))
+		if tt.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("#%d: expected error=%v, get=%v", i, tt.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: expected no error, get=%v", i, err)
+			continue
+		}
+		if len(c.Service.HTTP) != 1 || c.Service.HTTP[0].TimeOut != tt.expectedTimeout {
+			t.Errorf("#%d: expected timeout=%v, get=%+v", i, tt.expectedTimeout, c.Service.HTTP)
+		}
+	}
+}